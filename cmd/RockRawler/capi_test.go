@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// goString copies a nul-terminated C string, test files can't use cgo
+func goString(p unsafe.Pointer) string {
+	var b strings.Builder
+
+	for ; *(*byte)(p) != 0; p = unsafe.Add(p, 1) {
+		b.WriteByte(*(*byte)(p))
+	}
+
+	return b.String()
+}
+
+// next returns the element after p in a C array
+func next[T any](p *T) *T {
+	var zero T
+	return (*T)(unsafe.Add(unsafe.Pointer(p), unsafe.Sizeof(zero)))
+}
+
+func capiServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><a href="/a">a</a><a href="/b">b</a><script src="/app.js"></script></body></html>`))
+	}))
+}
+
+// The arrays of the C API are allocated and freed many times over, a double or invalid free
+// aborts the test. Run it with -asan to check that nothing leaks too
+func TestCStartCrawlerRoundTrip(t *testing.T) {
+	server := capiServer()
+	defer server.Close()
+
+	for i := 0; i < 50; i++ {
+		arr := CStartCrawler(server.URL, 1, 1, true, false, "", "", 0, 0)
+		if arr == nil {
+			t.Fatal("CStartCrawler returned NULL")
+		}
+
+		var found []string
+		for p := unsafe.Pointer(arr); *(*unsafe.Pointer)(p) != nil; p = unsafe.Add(p, unsafe.Sizeof(uintptr(0))) {
+			found = append(found, goString(*(*unsafe.Pointer)(p)))
+		}
+
+		CFreeResults(arr)
+
+		if len(found) != 3 {
+			t.Fatalf("CStartCrawler found %v, want the 3 links of the page", found)
+		}
+	}
+
+	// nothing to free
+	CFreeResults(nil)
+}
+
+func TestCStartCrawlerResultsRoundTrip(t *testing.T) {
+	server := capiServer()
+	defer server.Close()
+
+	for i := 0; i < 50; i++ {
+		arr := CStartCrawlerResults(server.URL, 1, 1, true, false, "", "", 0, 0)
+		if arr == nil {
+			t.Fatal("CStartCrawlerResults returned NULL")
+		}
+
+		found := make(map[string]string)
+		for p := arr; p.url != nil; p = next(p) {
+			found[goString(unsafe.Pointer(p.url))] = goString(unsafe.Pointer(p._type))
+
+			if source := goString(unsafe.Pointer(p.source)); source != server.URL || p.depth != 1 {
+				t.Fatalf("got source %q at depth %d, want %q at depth 1", source, p.depth, server.URL)
+			}
+		}
+
+		CFreeResultArray(arr)
+
+		if len(found) != 3 || found[server.URL+"/a"] != "href" || found[server.URL+"/app.js"] != "script" {
+			t.Fatalf("CStartCrawlerResults found %v, want the 3 links of the page", found)
+		}
+	}
+
+	CFreeResultArray(nil)
+}
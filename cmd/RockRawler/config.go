@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// defaultConfigPath is the config file read when -config isn't given, ~/.config/rockrawler/config.yml on Linux
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "rockrawler", "config.yml")
+}
+
+// flagAliases maps the flags that are another name of a flag to it
+var flagAliases = map[string]string{
+	"concurrency":   "c",
+	"unique-hosts":  "show-subs",
+	"timeout-total": "max-time",
+}
+
+// canonicalFlag is the name of the flag name is another name of, name itself for the others
+func canonicalFlag(name string) string {
+	if canonical, ok := flagAliases[name]; ok {
+		return canonical
+	}
+
+	return name
+}
+
+// setFlags returns the flags already set, by their canonical name
+func setFlags() map[string]bool {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		set[canonicalFlag(f.Name)] = true
+	})
+
+	return set
+}
+
+// envName is the environment variable of a flag, ROCKRAWLER_MAX_TIME for -max-time
+func envName(name string) string {
+	return "ROCKRAWLER_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// loadEnv sets the flags of the ROCKRAWLER_* environment variables that the command line didn't set.
+// Like on the command line, a repeatable flag takes a single value
+func loadEnv() error {
+	set := setFlags()
+
+	var err error
+	flag.VisitAll(func(f *flag.Flag) {
+		value, ok := os.LookupEnv(envName(f.Name))
+
+		if !ok || err != nil || set[canonicalFlag(f.Name)] {
+			return
+		}
+
+		if setErr := flag.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value of %s: %v", envName(f.Name), setErr)
+			return
+		}
+
+		set[canonicalFlag(f.Name)] = true
+	})
+
+	return err
+}
+
+// loadConfigFile sets the flags of a YAML config file that the command line didn't set, e.g.
+//
+//	d: 3
+//	subs: true
+//	h: "Cookie: session=1;;X-Bug-Bounty: me"
+//	deny: ["/admin/", "[?&]action=drop"]
+//
+// Keys are flag names without the dash, the repeatable flags take lists. A missing file is fine unless required
+func loadConfigFile(path string, required bool) error {
+	data, err := os.ReadFile(path)
+
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var values map[string]interface{}
+
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return err
+	}
+
+	// the command line and the environment win
+	set := setFlags()
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := flag.Lookup(name)
+
+		if f == nil || name == "config" {
+			return fmt.Errorf("unknown flag %q", name)
+		}
+
+		if set[canonicalFlag(name)] {
+			continue
+		}
+
+		list, isList := values[name].([]interface{})
+		_, repeatable := f.Value.(*listFlag)
+
+		if isList && !repeatable {
+			return fmt.Errorf("-%s takes a single value", name)
+		}
+
+		if !isList {
+			list = []interface{}{values[name]}
+		}
+
+		for _, value := range list {
+			if _, nested := value.(map[string]interface{}); nested || value == nil {
+				return fmt.Errorf("invalid value of -%s", name)
+			}
+
+			if err := flag.Set(name, fmt.Sprint(value)); err != nil {
+				return fmt.Errorf("invalid value of -%s: %v", name, err)
+			}
+		}
+	}
+
+	return nil
+}
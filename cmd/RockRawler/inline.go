@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"strings"
+
+	"github.com/abdallah-elsharif/RockRawler/crawler"
+)
+
+// inlineConfig applies the flags following the URL of an input line on top of the global config,
+// e.g. "https://example.com -d 3 -subs". Only a subset of the flags can be set per target
+func inlineConfig(base *crawler.Config, args []string) (*crawler.Config, error) {
+	cfg := *base
+	headers := ""
+
+	fs := flag.NewFlagSet("inline", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	fs.IntVar(&cfg.Depth, "d", cfg.Depth, "")
+	fs.IntVar(&cfg.Threads, "t", cfg.Threads, "")
+	fs.BoolVar(&cfg.SubsInScope, "subs", cfg.SubsInScope, "")
+	fs.BoolVar(&cfg.Insecure, "insecure", cfg.Insecure, "")
+	fs.StringVar(&headers, "h", "", "")
+	fs.StringVar(&cfg.Order, "order", cfg.Order, "")
+	fs.StringVar(&cfg.DefaultScheme, "default-scheme", cfg.DefaultScheme, "")
+	fs.StringVar(&cfg.HostOverride, "host-override", cfg.HostOverride, "")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if fs.NArg() > 0 {
+		return nil, errors.New("unexpected argument " + fs.Arg(0))
+	}
+
+	if cfg.Order != "" && cfg.Order != "bfs" && cfg.Order != "dfs" {
+		return nil, errors.New("invalid crawl order " + cfg.Order)
+	}
+
+	if cfg.DefaultScheme != "http" && cfg.DefaultScheme != "https" && cfg.DefaultScheme != "auto" {
+		return nil, errors.New("invalid default scheme " + cfg.DefaultScheme)
+	}
+
+	if cfg.HostOverride != base.HostOverride {
+		if cfg.Proxy != "" {
+			return nil, errors.New("-host-override can't be used with -proxy")
+		}
+
+		addr, err := crawler.ParseHostOverride(cfg.HostOverride)
+		if err != nil {
+			return nil, err
+		}
+		cfg.HostOverride = addr
+	}
+
+	// inline headers come after the global ones, so they win for the same header
+	if headers != "" {
+		if cfg.RawHeaders != "" {
+			cfg.RawHeaders += ";;"
+		}
+		cfg.RawHeaders += headers
+	}
+
+	return &cfg, nil
+}
+
+// splitArgs splits an input line into fields like a shell would, with single and double quotes
+func splitArgs(line string) ([]string, error) {
+	args := make([]string, 0)
+
+	var current strings.Builder
+	var quote rune
+	inArg := false
+
+	for _, ch := range line {
+		switch {
+		case quote != 0 && ch == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(ch)
+		case ch == '"' || ch == '\'':
+			quote = ch
+			inArg = true
+		case ch == ' ' || ch == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(ch)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
@@ -0,0 +1,1414 @@
+/*
+	Author	=> Abdallah Mohamed Elsharif
+	Email	=> elsharifabdallah53@gmail.com
+	Date	=> 3-1-2022
+*/
+
+// RockRawler crawls the URLs read from stdin and prints the URLs found, it's also built as a C archive.
+// The crawling itself is the crawler package
+package main
+
+/*
+#include <stdlib.h>
+
+// RockRawlerResult is a result of CStartCrawlerResults, type is the kind of reference (href, script, form, ...)
+typedef struct {
+	char *url;
+	char *source;
+	char *type;
+	int depth;
+} RockRawlerResult;
+
+// RockRawlerCallback is called by CStartCrawlerWithCallback with every URL found, result is only valid during the call
+typedef void (*RockRawlerCallback)(RockRawlerResult *result, void *userdata);
+
+// Go can't call a C function pointer
+static inline void callRockRawlerCallback(RockRawlerCallback callback, RockRawlerResult *result, void *userdata) {
+	callback(result, userdata);
+}
+*/
+import "C"
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"regexp"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/abdallah-elsharif/RockRawler/crawler"
+)
+
+// listFlag is a flag that can be repeated, collecting every value
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// splitDownloads separates the links carrying a download attribute from the rest
+func splitDownloads(results []crawler.Result) (rest []crawler.Result, downloads []crawler.Result) {
+	for _, result := range results {
+		if result.Download {
+			downloads = append(downloads, result)
+		} else {
+			rest = append(rest, result)
+		}
+	}
+
+	return rest, downloads
+}
+
+// writeHeapProfile writes a heap profile of the current process to path
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	// get up-to-date statistics before writing
+	runtime.GC()
+
+	return pprof.WriteHeapProfile(f)
+}
+
+// exit stops the CPU profile of -cpuprofile before exiting, os.Exit skips the deferred calls that would
+func exit(code int) {
+	pprof.StopCPUProfile()
+	os.Exit(code)
+}
+
+// CStartCrawler crawls url and returns a nul-terminated array of C strings with the URLs found.
+// The caller owns the array and its strings, it must pass it to CFreeResults exactly once.
+// delay and randomDelay are in milliseconds like -delay and -random-delay, 0 doesn't wait
+//
+//export CStartCrawler
+func CStartCrawler(url string, threads int, depth int, subsInScope bool, insecure bool, rawHeaders string, proxy string, delay int, randomDelay int) **C.char {
+
+	// Pass the supplied parameters from C to the crawler
+	results := cCrawl(context.Background(), url, cConfig(threads, depth, subsInScope, insecure, rawHeaders, proxy, delay, randomDelay))
+
+	// Get size of results to allocate memory for c results
+	size := len(results) + 1 // add one to put a nul terminator at the end of C strings array
+
+	// Allocate memory space for C array
+	cArray := C.malloc(C.size_t(size) * C.size_t(unsafe.Sizeof(uintptr(0))))
+
+	// convert the C array to a Go Array so we can index it
+	a := (*[1 << 28]*C.char)(unsafe.Pointer(cArray))[:size:size]
+
+	for idx, result := range results {
+		a[idx] = C.CString(result.URL)
+	}
+
+	// put a nul-terminator in the end of array
+	a[size-1] = nil
+
+	// return **char type to C
+	return (**C.char)(cArray)
+}
+
+// CFreeResults frees an array returned by CStartCrawler with every string in it.
+// The array can't be used afterwards, a nil array is ignored
+//
+//export CFreeResults
+func CFreeResults(arr **C.char) {
+	if arr == nil {
+		return
+	}
+
+	for p := arr; *p != nil; p = (**C.char)(unsafe.Add(unsafe.Pointer(p), unsafe.Sizeof(p))) {
+		C.free(unsafe.Pointer(*p))
+	}
+
+	C.free(unsafe.Pointer(arr))
+}
+
+// cConfig builds the crawler configuration from the parameters of the C API
+func cConfig(threads int, depth int, subsInScope bool, insecure bool, rawHeaders string, proxy string, delay int, randomDelay int) *crawler.Config {
+	return &crawler.Config{
+		Threads:     threads,
+		Depth:       depth,
+		SubsInScope: subsInScope,
+		Insecure:    insecure,
+		RawHeaders:  rawHeaders,
+		Proxy:       proxy,
+		Delay:       time.Duration(delay) * time.Millisecond,
+		RandomDelay: time.Duration(randomDelay) * time.Millisecond,
+		SafeMode:    true,
+		Log:         os.Stderr,
+	}
+}
+
+// CStartCrawlerResults is CStartCrawler returning a RockRawlerResult for every URL found, with the page it was found on and its depth.
+// The array ends with a result whose url is NULL, the caller must pass it to CFreeResultArray exactly once
+//
+//export CStartCrawlerResults
+func CStartCrawlerResults(url string, threads int, depth int, subsInScope bool, insecure bool, rawHeaders string, proxy string, delay int, randomDelay int) *C.RockRawlerResult {
+	results := cCrawl(context.Background(), url, cConfig(threads, depth, subsInScope, insecure, rawHeaders, proxy, delay, randomDelay))
+
+	return cResults(results)
+}
+
+// cResults copies results to a malloc'd array of RockRawlerResult terminated by a zeroed result
+func cResults(results []crawler.Result) *C.RockRawlerResult {
+	size := len(results) + 1
+
+	// calloc leaves the terminator zeroed
+	cArray := C.calloc(C.size_t(size), C.size_t(unsafe.Sizeof(C.RockRawlerResult{})))
+	a := unsafe.Slice((*C.RockRawlerResult)(cArray), size)
+
+	for idx, result := range results {
+		a[idx].url = C.CString(result.URL)
+		a[idx].source = C.CString(result.Source)
+		a[idx]._type = C.CString(result.Type)
+		a[idx].depth = C.int(result.Depth)
+	}
+
+	return (*C.RockRawlerResult)(cArray)
+}
+
+// CFreeResultArray frees an array returned by CStartCrawlerResults with every string in it.
+// The array can't be used afterwards, a nil array is ignored
+//
+//export CFreeResultArray
+func CFreeResultArray(arr *C.RockRawlerResult) {
+	if arr == nil {
+		return
+	}
+
+	for p := arr; p.url != nil; p = (*C.RockRawlerResult)(unsafe.Add(unsafe.Pointer(p), unsafe.Sizeof(*p))) {
+		C.free(unsafe.Pointer(p.url))
+		C.free(unsafe.Pointer(p.source))
+		C.free(unsafe.Pointer(p._type))
+	}
+
+	C.free(unsafe.Pointer(arr))
+}
+
+// crawlHandles holds the contexts of the handles returned by CNewCrawlHandle
+var crawlHandles = struct {
+	sync.Mutex
+	next    uintptr
+	ctx     map[uintptr]context.Context
+	cancels map[uintptr]context.CancelFunc
+}{ctx: map[uintptr]context.Context{}, cancels: map[uintptr]context.CancelFunc{}}
+
+// CNewCrawlHandle returns a handle that lets CCancelCrawl stop the CStartCrawlerWithCallback crawls it's given to.
+// It must be released by CFreeCrawlHandle
+//
+//export CNewCrawlHandle
+func CNewCrawlHandle() uintptr {
+	crawlHandles.Lock()
+	defer crawlHandles.Unlock()
+
+	crawlHandles.next++
+	handle := crawlHandles.next
+	crawlHandles.ctx[handle], crawlHandles.cancels[handle] = context.WithCancel(context.Background())
+
+	return handle
+}
+
+// CCancelCrawl stops the crawls of handle, they return soon after with the URLs found so far.
+// It's safe to call from any thread and more than once
+//
+//export CCancelCrawl
+func CCancelCrawl(handle uintptr) {
+	crawlHandles.Lock()
+	cancel := crawlHandles.cancels[handle]
+	crawlHandles.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// CFreeCrawlHandle cancels the crawls of handle and releases it, the handle can't be used afterwards
+//
+//export CFreeCrawlHandle
+func CFreeCrawlHandle(handle uintptr) {
+	CCancelCrawl(handle)
+
+	crawlHandles.Lock()
+	delete(crawlHandles.ctx, handle)
+	delete(crawlHandles.cancels, handle)
+	crawlHandles.Unlock()
+}
+
+// CStartCrawlerWithCallback crawls url like CStartCrawlerResults but hands every result to callback as soon as it's found,
+// along with userdata. The calls are made one at a time, from threads of the Go runtime. It returns once the crawl is done,
+// or cancelled through handle (0 for none), with the number of URLs found
+//
+//export CStartCrawlerWithCallback
+func CStartCrawlerWithCallback(handle uintptr, url string, threads int, depth int, subsInScope bool, insecure bool, rawHeaders string, proxy string, delay int, randomDelay int, callback C.RockRawlerCallback, userdata unsafe.Pointer) int {
+	ctx := context.Background()
+
+	crawlHandles.Lock()
+	if handleCtx, ok := crawlHandles.ctx[handle]; ok {
+		ctx = handleCtx
+	}
+	crawlHandles.Unlock()
+
+	var (
+		mu    sync.Mutex
+		found int
+	)
+
+	cfg := cConfig(threads, depth, subsInScope, insecure, rawHeaders, proxy, delay, randomDelay)
+	cfg.OnResult = func(result crawler.Result) {
+		r := C.RockRawlerResult{
+			url:    C.CString(result.URL),
+			source: C.CString(result.Source),
+			_type:  C.CString(result.Type),
+			depth:  C.int(result.Depth),
+		}
+
+		mu.Lock()
+		found++
+		if callback != nil {
+			C.callRockRawlerCallback(callback, &r, userdata)
+		}
+		mu.Unlock()
+
+		C.free(unsafe.Pointer(r.url))
+		C.free(unsafe.Pointer(r.source))
+		C.free(unsafe.Pointer(r._type))
+	}
+
+	cCrawl(ctx, url, cfg)
+
+	return found
+}
+
+// running holds the cancel functions of the crawls in progress started from C, for CStopCrawler
+var running = struct {
+	sync.Mutex
+	next    int
+	cancels map[int]context.CancelFunc
+}{cancels: map[int]context.CancelFunc{}}
+
+// cCrawl crawls url for the C API until ctx is done or CStopCrawler is called
+func cCrawl(ctx context.Context, url string, cfg *crawler.Config) []crawler.Result {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	running.Lock()
+	running.next++
+	id := running.next
+	running.cancels[id] = cancel
+	running.Unlock()
+
+	defer func() {
+		running.Lock()
+		delete(running.cancels, id)
+		running.Unlock()
+	}()
+
+	return crawler.StartCrawlerContext(ctx, url, cfg)
+}
+
+// CStopCrawler stops every crawl in progress, whichever function started it. They return soon after
+// with the URLs found so far, the crawls started afterwards aren't affected. It's safe to call from any thread
+//
+//export CStopCrawler
+func CStopCrawler() {
+	running.Lock()
+	defer running.Unlock()
+
+	for _, cancel := range running.cancels {
+		cancel()
+	}
+}
+
+func main() {
+	threads := flag.Int("t", 5, "Number of threads to utilise.")
+	concurrency := flag.Int("c", 3, "Number of targets crawled at once, each with its own -t threads.")
+	flag.IntVar(concurrency, "concurrency", 3, "Same as -c.")
+	depth := flag.Int("d", 2, "Depth to crawl.")
+	insecure := flag.Bool("insecure", false, "Disable TLS verification.")
+	acceptEncoding := flag.String("accept-encoding", "", "Accept-Encoding header of every request, e.g. \"gzip, br\". Only gzip bodies are decompressed.")
+	rawBody := flag.Bool("raw-body", false, "Don't decompress response bodies, links can't be extracted from compressed pages then.")
+	timeout := flag.Int("timeout", 10, "Timeout of a request in seconds, retries get a new one.")
+	proxy := flag.String("proxy", "", "Proxy of every request, e.g. http://127.0.0.1:8080 or socks5://127.0.0.1:1080. Comma-separated proxies take turns. Overrides HTTPS_PROXY/HTTP_PROXY.")
+	caCert := flag.String("cacert", "", "PEM file of a CA to trust for TLS verification, e.g. the CA of an interception proxy.")
+	subsInScope := flag.Bool("subs", false, "Include subdomains for crawling.")
+	digest := flag.String("digest", "", "Credentials for HTTP Digest authentication. E.g. -digest admin:secret")
+	rawCookies := flag.String("cookie", "", "Cookies every crawl starts with, like a Cookie header. E.g. -cookie \"session=abc; lang=en\". The servers can replace them.")
+	loginURL := flag.String("login-url", "", "URL (or path on the target) -login-data is posted to before crawling, the session cookies it sets are crawled with.")
+	loginData := flag.String("login-data", "", "Body posted to -login-url, form-encoded (user=admin&pass=secret) or JSON when it starts with {.")
+	rawHeaders := flag.String(("h"), "", "Custom headers separated by two semi-colons. E.g. -h \"Cookie: foo=bar;;Referer: http://example.com/\" ")
+	userAgent := flag.String("ua", "", "User-Agent of the requests, a preset (desktop, mobile, googlebot) or the header itself. Firefox 78 by default.")
+	randomAgent := flag.Bool("ua-rand", false, "Pick the User-Agent of every request from a built-in list of current browsers.")
+	cpuProfile := flag.String("cpuprofile", "", "Write a CPU profile of the crawl to the specified file.")
+	memProfile := flag.String("memprofile", "", "Write a heap profile to the specified file when crawling finishes.")
+	asJSON := flag.Bool("json", false, "Output results as JSON, one object per line.")
+	asCSV := flag.Bool("csv", false, "Output results as CSV rows of url, source, depth, type and status, after a header row.")
+	uniqueStorePath := flag.String("unique-store", "", "File of the URLs written by previous runs, only the new ones are written and then added to it.")
+	since := flag.String("since", "", "With -unique-store, also write the URLs first seen after this time (RFC 3339 or YYYY-MM-DD) or this long ago (e.g. 24h).")
+	count := flag.Bool("count", false, "Output how many times each URL was referenced, as count<TAB>url lines sorted by count.")
+	pathsOnly := flag.Bool("paths-only", false, "Output the unique paths (with their query) of the URLs across all targets, without the host.")
+	showSubs := flag.Bool("show-subs", false, "Output the unique hostnames of the URLs across all targets, from every source, e.g. to resolve them.")
+	flag.BoolVar(showSubs, "unique-hosts", false, "Same as -show-subs.")
+	ignoreQuery := flag.Bool("ignore-query", false, "Leave the query out of -paths-only paths.")
+	outFile := flag.String("o", "", "Write the results to the specified file instead of stdout.")
+	splitDir := flag.String("split", "", "Also write the results of each crawled host to <host>.txt (or .json) in the specified directory.")
+	where := flag.Bool("where", false, "Start each URL with the page it was found on and its type, as source<TAB>type<TAB>url lines. JSON and CSV always have them.")
+	grouped := flag.Bool("grouped", false, "Start the results of each target with a header line naming it.")
+	burp := flag.Bool("burp", false, "Output a unique list of absolute http(s) URLs to seed Burp Suite with.")
+	downloads := flag.String("downloads", "", "Write links carrying a download attribute to the specified file instead of the results.")
+	linksOut := flag.String("links-out", "", "Write links (anchors, image map areas and Link headers) to the specified file instead of the results.")
+	scriptsOut := flag.String("scripts-out", "", "Write script URLs to the specified file instead of the results.")
+	formsOut := flag.String("forms-out", "", "Write form actions to the specified file instead of the results.")
+	modulesOut := flag.String("modules-out", "", "Write JavaScript module imports (-modules) to the specified file instead of the results.")
+	modules := flag.Bool("modules", false, "Follow JavaScript modules and record the modules they import.")
+	structuredData := flag.Bool("structured-data", false, "Record the URLs of JSON-LD blocks and microdata properties.")
+	detectSoft404 := flag.Bool("detect-soft404", false, "Suppress pages that look like the site's response to a missing page.")
+	hostThreshold := flag.Int("host-threshold", 0, "Pages crawled on a host other than the target before its depth is capped to -host-depth (0 disables).")
+	hostDepth := flag.Int("host-depth", 1, "Depth cap for hosts past -host-threshold.")
+	hsts := flag.Bool("hsts", false, "Upgrade http links to https on hosts that sent a Strict-Transport-Security header.")
+	var appendParams listFlag
+	flag.Var(&appendParams, "append-param", "Query parameter added to every request, can be repeated. E.g. -append-param api_key=secret")
+	autoReferer := flag.Bool("auto-referer", false, "Send the page a link was found on as the Referer of its request.")
+	estimate := flag.Bool("estimate", false, "Only crawl the starting page and print an estimate of the requests a full crawl would make, to stdout or -o.")
+	robots := flag.Bool("robots", false, "Record the paths of the Allow and Disallow rules of robots.txt, and crawl the in-scope ones (disallowed ones with -ignore-robots).")
+	archive := flag.String("archive", "", "Comma-separated web archives to record the URLs they saw on the target from: wayback, otx, commoncrawl or all.")
+	archiveCrawl := flag.Bool("archive-crawl", false, "Crawl the in-scope URLs found by -archive too.")
+	sitemap := flag.Bool("sitemap", false, "Record the URLs of /sitemap.xml and the sitemaps robots.txt lists, and crawl the in-scope ones.")
+	manifests := flag.Bool("manifests", false, "Follow web app manifests and service workers, and record and follow the URLs they list.")
+	all := flag.Bool("all", false, "Also record the URLs of <link>, <img> and <source> elements (stylesheets, icons, images, media), of srcset attributes and of HTML comments, without fetching them.")
+	js := flag.Bool("js", false, "Fetch the in-scope scripts and record the URLs and paths quoted in them and in inline scripts.")
+	followIframes := flag.Bool("follow-iframes", false, "Follow the in-scope iframe sources like links.")
+	render := flag.Bool("render", false, "Load the HTML pages in headless Chrome and extract the links of the rendered DOM and the XHR/fetch URLs. Needs Chrome or Chromium.")
+	forms := flag.Bool("forms", false, "Record the method and fields of forms, those without action too, and the parameter names of every URL (in JSON output).")
+	tabnabbing := flag.Bool("tabnabbing", false, "Report links opening a new window (target=_blank) without rel=\"noopener\" on stderr and in JSON output.")
+	hashBodies := flag.Bool("hash", false, "Record the SHA-256 of the body of every fetched page (in JSON output).")
+	robotsMeta := flag.Bool("robots-meta", false, "Record the <meta name=\"robots\"> and X-Robots-Tag directives of visited pages (in JSON output).")
+	summary := flag.Bool("summary-hash", false, "Print a hash of each target's normalized URL set instead of the URLs (to stdout or -o), to detect changes between runs.")
+	showStatus := flag.Bool("show-status", false, "Record the status, content type, length and redirects of every visited page (in JSON and CSV output).")
+	verify := flag.Bool("verify", false, "Request every recorded http(s) URL after crawling and record its status (in JSON output).")
+	liveOnly := flag.Bool("live-only", false, "Like -verify, but only output the URLs answering with a status below 400.")
+	timingProfile := flag.String("timing-profile", "", "File of weighted delay ranges every request waits for (see README). No delay by default.")
+	match := flag.String("match", "", "Regex of the URLs to record, the others are still crawled. Everything is recorded by default.")
+	filterOut := flag.String("filter-out", "", "Regex of the URLs not to record (e.g. static assets), they are still crawled.")
+	expandIf := flag.String("expand-if", "", "Regex of page bodies whose links are followed, the links of other pages are only recorded.")
+	capture := flag.String("capture", "", "Regex of URLs whose full request and response are saved to -capture-dir, one file per URL.")
+	captureDir := flag.String("capture-dir", "captures", "Directory the -capture exchanges are written to.")
+	sample := flag.Float64("sample", 1, "Fraction of the discovered links to follow, e.g. 0.2. Every link is still recorded.")
+	seed := flag.Int64("seed", 0, "Seed of the random choices (-sample, -timing-profile delays), to reproduce a crawl (0 picks a random seed).")
+	delay := flag.Int("delay", 0, "Milliseconds to wait after each request of the crawl, whatever its host.")
+	randomDelay := flag.Int("random-delay", 0, "Maximum milliseconds added at random to -delay.")
+	maxDNS := flag.Int("max-dns", 0, "Maximum number of concurrent DNS lookups (0 doesn't limit them).")
+	hostOverride := flag.String("host-override", "", "Connect to this address (IP or host, optional port) for the target's host, e.g. a vhost or the origin behind a CDN. URLs, Host header and SNI stay the target's.")
+	ignoreRobots := flag.Bool("ignore-robots", false, "Don't fetch robots.txt. By default the in-scope URLs it disallows are neither crawled nor recorded.")
+	stats := flag.Bool("stats", false, "Print a line per target to stderr with its URLs, requests, errors and crawl time, progress lines meanwhile and a summary of the run at the end.")
+	statsInterval := flag.Duration("stats-interval", 10*time.Second, "How often -stats prints a progress line (0 only prints the per-target lines and the summary).")
+	metricsAddr := flag.String("metrics", "", "Serve Prometheus metrics of the run (requests, errors by type, statuses, queue) at /metrics on this address, e.g. 127.0.0.1:9090.")
+	maxTime := flag.Duration("max-time", 0, "Stop crawling a target after this duration (e.g. 5m), keeping the results found so far. Not limited by default.")
+	flag.DurationVar(maxTime, "timeout-total", 0, "Same as -max-time.")
+	maxRedirects := flag.Int("max-redirects", 10, "Redirects followed per request, the target of the first one not followed is recorded with type redirect.")
+	followRedirects := flag.String("follow-redirects", "all", "Redirects followed: all, scope (not the ones leaving the crawl scope) or none. The targets of the others are recorded with type redirect.")
+	maxRequests := flag.Int("max-requests", 0, "Stop crawling a target after this many page requests, keeping the results found so far. Not limited by default.")
+	serve := flag.String("serve", "", "Take crawl jobs over an HTTP API on this address (e.g. 127.0.0.1:8080) instead of reading targets from stdin, until Ctrl+C. The API has no authentication, bind it to loopback.")
+	serveTTL := flag.Duration("serve-ttl", time.Hour, "How long -serve keeps the jobs that ended, 0 until it stops. It keeps the last 1000 at most.")
+	batchMaxTime := flag.Duration("batch-maxtime", 0, "Stop the whole run after this duration (e.g. 2h), writing the results found so far. Not limited by default.")
+	failFast := flag.Bool("fail-fast", false, "Stop crawling a target on its first failed request and report the error, to debug a configuration.")
+	tlsInfo := flag.String("tls-info", "", "Write the certificate subject, issuer and SANs of every HTTPS host to the specified file, one JSON object per host.")
+	expandSANs := flag.Bool("expand-sans", false, "Crawl the hostnames of certificate SANs sharing the target's registrable domain as new seeds.")
+	maxHosts := flag.Int("max-hosts", crawler.DefaultMaxHosts, "Maximum number of SAN hosts -expand-sans seeds per target.")
+	scheme := flag.String("default-scheme", "http", "Scheme of the URLs given without one: http, https, or auto (https, falling back to http).")
+	maxParams := flag.Int("max-params", 0, "Neither record nor crawl URLs with more query parameters than this, they're usually tracking junk (0 disables the check).")
+	cookieFile := flag.String("cookie-file", "", "Cookie file (Netscape/cURL format) every crawl starts with, the cookies the servers set are saved back to it.")
+	validators := flag.String("validators", "", "File of the ETag/Last-Modified of crawled pages. Re-crawls send them back and record pages answering 304 as unchanged without following their links.")
+	force := flag.Bool("force", false, "With -validators, request every page unconditionally and only refresh the file.")
+	stateFile := flag.String("state", "", "Checkpoint the progress of the crawls (pending links, visited pages, results) to the specified file as they run.")
+	resume := flag.Bool("resume", false, "With -state, pick the crawls up where the run that wrote the file stopped instead of starting from scratch.")
+	retries := flag.Int("retries", 0, "Number of times failed requests and the ones answering a -retry-codes status are retried.")
+	retryCodes := flag.String("retry-codes", "429,500,502,503,504", "Comma-separated statuses that make -retries retry a request.")
+	retryBackoff := flag.Duration("retry-backoff", time.Second, "Wait before the first retry, it doubles with every retry. Retry-After takes precedence, up to 5m.")
+	maxLinks := flag.Int("max-links", crawler.DefaultMaxLinks, "Links extracted from a single page beyond this number are ignored (0 disables the cap).")
+	eventsOut := flag.String("events-out", "", "Stream crawl events (visit, found, error, retry) as NDJSON to the specified file.")
+	harFile := flag.String("har", "", "HAR file of a browser session, its in-scope GET requests are crawled as extra seeds of every target.")
+	harOut := flag.String("har-out", "", "Write every request and response of the crawls to a HAR file, for Burp, ZAP or the browser devtools.")
+	harCookies := flag.Bool("har-cookies", false, "Send the cookies recorded in the -har file with the requests to their hosts.")
+	asnDB := flag.String("asn-db", "", "IP to ASN database (iptoasn.com TSV format) for -asn and -country. No filtering without it.")
+	asns := flag.String("asn", "", "Comma-separated AS numbers the crawled hosts must resolve to, e.g. AS13335,15169. Requires -asn-db.")
+	countries := flag.String("country", "", "Comma-separated country codes the crawled hosts must resolve to, e.g. US,DE. Requires -asn-db.")
+	maxGoroutines := flag.Int("max-goroutines", 0, "Ceiling of the goroutines visiting links, shared by the crawls of the run, they queue up instead of getting one each (0 doesn't cap them).")
+	verbose := flag.Bool("verbose", false, "Print warnings about ignored links and skipped work to stderr.")
+	includeRegex := flag.String("include-regex", "", "Regex of the links to follow, the others are recorded but not visited.")
+	excludeRegex := flag.String("exclude-regex", "", "Regex of the links not to follow, they're still recorded.")
+	var denyList listFlag
+	flag.Var(&denyList, "deny", "Regexes of the URLs never to request, not even through a redirect, comma-separated or repeated. They're still recorded. E.g. -deny '/admin/,action=purge'")
+	safe := flag.Bool("safe", true, "Never request logout, sign out, delete and similar links, they're still recorded. -safe=false requests them.")
+	ignoreExt := flag.String("ignore-ext", "", "Comma-separated extensions of links not to follow, e.g. png,jpg,gif,svg,css,woff,woff2. They're still recorded.")
+	ports := flag.String("ports", "", "Comma-separated ports links are followed on, e.g. -ports 443,8443. All ports by default.")
+	unique := flag.Bool("unique", false, "Record a URL once whatever its spelling: case of the scheme and host, default port, fragment and order of the query parameters.")
+	uniqueSlash := flag.Bool("unique-slash", false, "Like -unique, and a trailing slash doesn't make a URL different either (/a/ and /a).")
+	exact := flag.Bool("exact", false, "Fetch every input URL verbatim (it needs a scheme) and only record its links, nothing is followed.")
+	order := flag.String("order", "", "Crawl order, bfs (level by level) or dfs (deepest first). By default links are visited concurrently as they are found.")
+	recordExternal := flag.Bool("record-external", true, "Record the URLs outside the crawl scope. They are never followed, -record-external=false leaves them out.")
+	skipCDN := flag.Bool("skip-cdn", false, "Neither record nor crawl URLs on common CDN and third-party hosts.")
+	cdnList := flag.String("cdn-list", "", "File with additional hosts for -skip-cdn, one per line.")
+	scopeExpr := flag.String("scope-expr", "", "CEL expression deciding which links are followed. E.g. -scope-expr 'host.endsWith(\"example.com\") && path.startsWith(\"/api\")'")
+	configFile := flag.String("config", "", "YAML file of flag defaults (flag names without the dash as keys), the ROCKRAWLER_* environment variables and the command line override them. Default: ~/.config/rockrawler/config.yml when it exists.")
+
+	flag.Parse()
+
+	// Take the defaults of the ROCKRAWLER_* environment variables
+	if err := loadEnv(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// then of the config file, a -config file must exist
+	configPath := *configFile
+	if configPath == "" {
+		configPath = defaultConfigPath()
+	}
+
+	if configPath != "" {
+		if err := loadConfigFile(configPath, *configFile != ""); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid config file %s: %v\n", configPath, err)
+			os.Exit(1)
+		}
+	}
+
+	cfg := &crawler.Config{
+		Threads:        *threads,
+		Depth:          *depth,
+		SubsInScope:    *subsInScope,
+		Insecure:       *insecure,
+		AcceptEncoding: *acceptEncoding,
+		RawBody:        *rawBody,
+		RawHeaders:     *rawHeaders,
+		RawCookies:     *rawCookies,
+		UserAgent:      *userAgent,
+		RandomAgent:    *randomAgent,
+		LoginURL:       *loginURL,
+		LoginData:      *loginData,
+		DigestAuth:     *digest,
+		Order:          *order,
+		Modules:        *modules,
+		DetectSoft404:  *detectSoft404,
+		StructuredData: *structuredData,
+		HostThreshold:  *hostThreshold,
+		HostDepth:      *hostDepth,
+		HSTS:           *hsts,
+		AutoReferer:    *autoReferer,
+		SkipExternal:   !*recordExternal,
+		Exact:          *exact,
+		Unique:         *unique,
+		UniqueSlash:    *uniqueSlash,
+		RobotsMeta:     *robotsMeta,
+		HashBodies:     *hashBodies,
+		Tabnabbing:     *tabnabbing,
+		Forms:          *forms,
+		Render:         *render,
+		FollowIframes:  *followIframes,
+		JS:             *js,
+		All:            *all,
+		Manifests:      *manifests,
+		Sitemap:        *sitemap,
+		Robots:         *robots,
+		ArchiveCrawl:   *archiveCrawl,
+		Verify:         *verify,
+		ShowStatus:     *showStatus,
+		LiveOnly:       *liveOnly,
+		Sample:         *sample,
+		Seed:           *seed,
+		MaxDNS:         *maxDNS,
+		Delay:          time.Duration(*delay) * time.Millisecond,
+		RandomDelay:    time.Duration(*randomDelay) * time.Millisecond,
+		FailFast:       *failFast,
+		SafeMode:       *safe,
+		MaxRequests:    *maxRequests,
+		MaxRedirects:   *maxRedirects,
+		Retries:        *retries,
+		RetryBackoff:   *retryBackoff,
+		MaxParams:      *maxParams,
+		DefaultScheme:  *scheme,
+		ExpandSANs:     *expandSANs,
+		MaxHosts:       *maxHosts,
+		MaxLinks:       *maxLinks,
+		Proxy:          *proxy,
+		IgnoreRobots:   *ignoreRobots,
+		Timeout:        time.Duration(*timeout) * time.Second,
+		MaxGoroutines:  *maxGoroutines,
+		Verbose:        *verbose,
+		Log:            os.Stderr,
+	}
+
+	// one -max-goroutines ceiling for all the crawls of the run, running at once with -c and -serve
+	if *maxGoroutines > 0 {
+		cfg.Goroutines = crawler.NewGoroutineLimit(*maxGoroutines)
+	}
+
+	if *estimate && *depth < 1 {
+		fmt.Fprintln(os.Stderr, "-estimate needs a limited depth (-d 1 or more)")
+		os.Exit(1)
+	}
+
+	if *scheme != "http" && *scheme != "https" && *scheme != "auto" {
+		fmt.Fprintln(os.Stderr, "Invalid default scheme:", *scheme, "(expected http, https or auto)")
+		os.Exit(1)
+	}
+
+	if *digest != "" && !strings.Contains(*digest, ":") {
+		fmt.Fprintln(os.Stderr, "Invalid -digest (expected user:password)")
+		os.Exit(1)
+	}
+
+	if *sample <= 0 || *sample > 1 {
+		fmt.Fprintln(os.Stderr, "-sample must be greater than 0 and at most 1")
+		os.Exit(1)
+	}
+
+	if *order != "" && *order != "bfs" && *order != "dfs" {
+		fmt.Fprintln(os.Stderr, "Invalid crawl order:", *order, "(expected bfs or dfs)")
+		os.Exit(1)
+	}
+
+	// Trust the extra CA if -cacert is present
+	if *caCert != "" {
+		pool, err := crawler.LoadCACert(*caCert)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not load CA certificate:", err)
+			os.Exit(1)
+		}
+		cfg.RootCAs = pool
+	}
+
+	// Load the delays if -timing-profile is present
+	if *timingProfile != "" {
+		profile, err := crawler.LoadTimingProfile(*timingProfile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid timing profile:", err)
+			os.Exit(1)
+		}
+		cfg.TimingProfile = profile
+	}
+
+	// Compile the result filters if -match or -filter-out are present
+	if *match != "" {
+		re, err := regexp.Compile(*match)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid -match regex:", err)
+			os.Exit(1)
+		}
+		cfg.Match = re
+	}
+
+	if *filterOut != "" {
+		re, err := regexp.Compile(*filterOut)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid -filter-out regex:", err)
+			os.Exit(1)
+		}
+		cfg.FilterOut = re
+	}
+
+	// Compile the body rule if -expand-if is present
+	if *expandIf != "" {
+		re, err := regexp.Compile(*expandIf)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid -expand-if regex:", err)
+			os.Exit(1)
+		}
+		cfg.ExpandIf = re
+	}
+
+	// Prepare the capture directory if -capture is present
+	if *capture != "" {
+		re, err := regexp.Compile(*capture)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid -capture regex:", err)
+			os.Exit(1)
+		}
+
+		if err := os.MkdirAll(*captureDir, 0755); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not create capture directory:", err)
+			os.Exit(1)
+		}
+
+		cfg.Capture = re
+		cfg.CaptureDir = *captureDir
+	}
+
+	// Every target would fail with an invalid -proxy
+	if *proxy != "" {
+		if _, err := crawler.ParseProxies(*proxy); err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid proxy:", err)
+			os.Exit(1)
+		}
+	}
+
+	// Load the browser session if -har is present
+	if *harFile != "" {
+		session, err := crawler.LoadHAR(*harFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not load HAR file:", err)
+			os.Exit(1)
+		}
+		cfg.HAR = session
+		cfg.HARCookies = *harCookies
+	}
+
+	// Load the IP ranges if -asn-db is present, it needs something to filter on
+	if *asnDB != "" {
+		if *asns == "" && *countries == "" {
+			fmt.Fprintln(os.Stderr, "-asn-db needs -asn or -country")
+			os.Exit(1)
+		}
+
+		filter, err := crawler.LoadInfraFilter(*asnDB)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not load ASN database:", err)
+			os.Exit(1)
+		}
+
+		if *asns != "" {
+			if filter.ASNs, err = crawler.ParseASNs(*asns); err != nil {
+				fmt.Fprintln(os.Stderr, "Invalid -asn:", err)
+				os.Exit(1)
+			}
+		}
+
+		if *countries != "" {
+			if filter.Countries, err = crawler.ParseCountries(*countries); err != nil {
+				fmt.Fprintln(os.Stderr, "Invalid -country:", err)
+				os.Exit(1)
+			}
+		}
+
+		cfg.Infra = filter
+	}
+
+	// Load the cookies of the previous run if -cookie-file is present
+	if *cookieFile != "" {
+		cookies, err := crawler.LoadCookieFile(*cookieFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not load cookies:", err)
+			os.Exit(1)
+		}
+		cfg.Cookies = cookies
+	}
+
+	// Load the validators of the previous run if -validators is present
+	if *validators != "" {
+		store, err := crawler.LoadValidatorStore(*validators)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not load validators:", err)
+			os.Exit(1)
+		}
+		cfg.Validators = store
+		cfg.ForceRefresh = *force
+	}
+
+	// Checkpoint the crawls if -state is present, starting from the previous checkpoint with -resume
+	if *resume && *stateFile == "" {
+		fmt.Fprintln(os.Stderr, "-resume needs -state")
+		os.Exit(1)
+	}
+
+	if *stateFile != "" {
+		cfg.State = crawler.NewCrawlState(*stateFile)
+
+		if *resume {
+			state, err := crawler.LoadCrawlState(*stateFile)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Could not load the crawl state:", err)
+				os.Exit(1)
+			}
+			cfg.State = state
+		}
+	}
+
+	// Parse the statuses -retries retries
+	codes, err := crawler.ParseStatuses(*retryCodes)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid -retry-codes:", err)
+		os.Exit(1)
+	}
+	cfg.RetryCodes = codes
+
+	for _, list := range denyList {
+		regexes, err := crawler.ParseDenyList(list)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid -deny regex:", err)
+			os.Exit(1)
+		}
+		cfg.DenyRegex = append(cfg.DenyRegex, regexes...)
+	}
+
+	if *rawCookies != "" {
+		if _, err := http.ParseCookie(*rawCookies); err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid -cookie:", err)
+			os.Exit(1)
+		}
+	}
+
+	if *loginData != "" && *loginURL == "" {
+		fmt.Fprintln(os.Stderr, "-login-data needs -login-url")
+		os.Exit(1)
+	}
+
+	if *maxRedirects < 1 {
+		fmt.Fprintln(os.Stderr, "Invalid -max-redirects, -follow-redirects none disables redirects")
+		os.Exit(1)
+	}
+
+	policy, err := crawler.ParseRedirectPolicy(*followRedirects)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid -follow-redirects:", err)
+		os.Exit(1)
+	}
+	cfg.FollowRedirects = policy
+
+	if *userAgent != "" && *randomAgent {
+		fmt.Fprintln(os.Stderr, "-ua can't be used with -ua-rand")
+		os.Exit(1)
+	}
+
+	// the proxy would resolve the target itself
+	if *hostOverride != "" && *proxy != "" {
+		fmt.Fprintln(os.Stderr, "-host-override can't be used with -proxy")
+		os.Exit(1)
+	}
+
+	if *hostOverride != "" {
+		addr, err := crawler.ParseHostOverride(*hostOverride)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid -host-override:", err)
+			os.Exit(1)
+		}
+		cfg.HostOverride = addr
+	}
+
+	if *archive != "" {
+		archives, err := crawler.ParseArchives(*archive)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid -archive:", err)
+			os.Exit(1)
+		}
+		cfg.Archive = archives
+	}
+
+	// Parse the allowed ports if -ports is present
+	if *ports != "" {
+		list, err := crawler.ParsePorts(*ports)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid -ports:", err)
+			os.Exit(1)
+		}
+		cfg.Ports = list
+	}
+
+	// Compile the visit filters if -include-regex, -exclude-regex or -ignore-ext are present
+	if *includeRegex != "" {
+		re, err := regexp.Compile(*includeRegex)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid -include-regex regex:", err)
+			os.Exit(1)
+		}
+		cfg.IncludeRegex = re
+	}
+
+	if *excludeRegex != "" {
+		re, err := regexp.Compile(*excludeRegex)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid -exclude-regex regex:", err)
+			os.Exit(1)
+		}
+		cfg.ExcludeRegex = re
+	}
+
+	if *ignoreExt != "" {
+		exts, err := crawler.ParseExtensions(*ignoreExt)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid -ignore-ext:", err)
+			os.Exit(1)
+		}
+		cfg.IgnoreExt = exts
+	}
+
+	// Parse the parameters added to every request
+	for _, param := range appendParams {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok || key == "" {
+			fmt.Fprintln(os.Stderr, "Invalid -append-param (expected key=value):", param)
+			os.Exit(1)
+		}
+
+		if cfg.AppendParams == nil {
+			cfg.AppendParams = url.Values{}
+		}
+		cfg.AppendParams.Add(key, value)
+	}
+
+	// Collect the hosts to skip if -skip-cdn is present
+	if *skipCDN {
+		cfg.SkipHosts = append(cfg.SkipHosts, crawler.DefaultCDNHosts...)
+
+		if *cdnList != "" {
+			hosts, err := crawler.ReadList(*cdnList)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Could not read CDN list:", err)
+				os.Exit(1)
+			}
+			cfg.SkipHosts = append(cfg.SkipHosts, hosts...)
+		}
+	}
+
+	// Compile the scope expression once, before any crawling starts
+	if *scopeExpr != "" {
+		expr, err := crawler.CompileScopeExpr(*scopeExpr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid scope expression:", err)
+			os.Exit(1)
+		}
+		cfg.ScopeExpr = expr
+	}
+
+	// Check for stdin input, -serve takes its targets over HTTP
+	stat, _ := os.Stdin.Stat()
+	if *serve == "" && (stat.Mode()&os.ModeCharDevice) != 0 {
+		fmt.Fprintln(os.Stderr, "No urls detected. Hint: cat urls.txt | RockRawler")
+		os.Exit(1)
+	}
+
+	// Profile the whole crawl if -cpuprofile is present
+	if *cpuProfile != "" {
+		f, err := os.Create(*cpuProfile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not create CPU profile:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+
+		if err := pprof.StartCPUProfile(f); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not start CPU profile:", err)
+			os.Exit(1)
+		}
+		defer pprof.StopCPUProfile()
+	}
+
+	format := formatPlain
+	formats := 0
+	for _, set := range []bool{*asJSON, *asCSV, *burp, *count, *pathsOnly, *showSubs} {
+		if set {
+			formats++
+		}
+	}
+
+	if formats > 1 {
+		fmt.Fprintln(os.Stderr, "Only one of -json, -csv, -burp, -count, -paths-only and -show-subs can be used")
+		exit(1)
+	} else if *asJSON {
+		format = formatJSON
+	} else if *asCSV {
+		format = formatCSV
+	} else if *burp {
+		format = formatBurp
+	} else if *count {
+		format = formatCount
+	} else if *pathsOnly {
+		format = formatPaths
+	} else if *showSubs {
+		format = formatHosts
+	}
+
+	// the other lists have no room for the source
+	if *where && (*burp || *count || *pathsOnly || *showSubs) {
+		fmt.Fprintln(os.Stderr, "-where can't be used with -burp, -count, -paths-only or -show-subs")
+		exit(1)
+	}
+
+	// Burp imports a bare URL list and CSV has a single header, headers would break them
+	if *grouped && (*burp || *asCSV) {
+		fmt.Fprintln(os.Stderr, "-grouped can't be used with -burp or -csv")
+		exit(1)
+	}
+
+	// the jobs of -serve answer with their results, nothing is written to the output
+	if *serve != "" {
+		for _, output := range []struct {
+			name string
+			set  bool
+		}{
+			{"o", *outFile != ""},
+			{"json", *asJSON},
+			{"csv", *asCSV},
+			{"burp", *burp},
+			{"count", *count},
+			{"paths-only", *pathsOnly},
+			{"show-subs", *showSubs},
+			{"where", *where},
+			{"grouped", *grouped},
+			{"split", *splitDir != ""},
+			{"downloads", *downloads != ""},
+			{"links-out", *linksOut != ""},
+			{"scripts-out", *scriptsOut != ""},
+			{"forms-out", *formsOut != ""},
+			{"modules-out", *modulesOut != ""},
+			{"unique-store", *uniqueStorePath != ""},
+			{"summary-hash", *summary},
+			{"estimate", *estimate},
+		} {
+			if output.set {
+				fmt.Fprintf(os.Stderr, "-%s can't be used with -serve\n", output.name)
+				exit(1)
+			}
+		}
+	}
+
+	// Only write the URLs previous runs didn't if -unique-store is present
+	var seenStore *uniqueStore
+	if *uniqueStorePath != "" {
+		store, err := loadUniqueStore(*uniqueStorePath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not load unique store:", err)
+			exit(1)
+		}
+		seenStore = store
+	}
+
+	// and the ones they found after -since
+	if *since != "" {
+		if seenStore == nil {
+			fmt.Fprintln(os.Stderr, "-since needs -unique-store")
+			exit(1)
+		}
+
+		t, err := parseSince(*since)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid -since:", err)
+			exit(1)
+		}
+		seenStore.since = t
+	}
+
+	// every output shares the format and its settings
+	open := func(w io.Writer) *output {
+		o := newOutput(w, format)
+		o.ignoreQuery = *ignoreQuery
+		o.grouped = *grouped
+		o.where = *where
+		return o
+	}
+
+	// Write the results to a file instead of stdout if -o is present
+	stdout := open(os.Stdout)
+	if *outFile != "" {
+		f, err := os.Create(*outFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not create output file:", err)
+			exit(1)
+		}
+		defer f.Close()
+
+		w := bufio.NewWriter(f)
+		defer w.Flush()
+
+		stdout = open(w)
+	}
+
+	// and to a file per crawled host too if -split is present
+	var split *splitOutput
+	if *splitDir != "" {
+		if err := os.MkdirAll(*splitDir, 0755); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not create split directory:", err)
+			exit(1)
+		}
+		split = newSplitOutput(*splitDir, open)
+	}
+
+	// Open the downloads list if -downloads is present
+	var downloadsList *output
+	if *downloads != "" {
+		f, err := os.Create(*downloads)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not create downloads list:", err)
+			exit(1)
+		}
+		defer f.Close()
+		downloadsList = open(f)
+	}
+
+	// Open a file per routed result type, types sharing a file share its output
+	routes := make(map[string]*output)
+	byPath := make(map[string]*output)
+
+	for _, route := range []struct {
+		path  string
+		types []string
+	}{
+		{*linksOut, []string{"href", "area", "link-header"}},
+		{*scriptsOut, []string{"script"}},
+		{*formsOut, []string{"form"}},
+		{*modulesOut, []string{"module"}},
+	} {
+		if route.path == "" {
+			continue
+		}
+
+		o, ok := byPath[route.path]
+		if !ok {
+			f, err := os.Create(route.path)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Could not create output file:", err)
+				exit(1)
+			}
+			defer f.Close()
+
+			o = open(f)
+			byPath[route.path] = o
+		}
+
+		for _, kind := range route.types {
+			routes[kind] = o
+		}
+	}
+
+	// Stream the events of every target if -events-out is present
+	if *eventsOut != "" {
+		f, err := os.Create(*eventsOut)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not create events file:", err)
+			exit(1)
+		}
+		defer f.Close()
+
+		cfg.Events = crawler.NewEventLog(f)
+	}
+
+	// Record the exchanges of every target in one archive if -har-out is present
+	if *harOut != "" {
+		f, err := os.Create(*harOut)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not create HAR file:", err)
+			exit(1)
+		}
+		defer f.Close()
+
+		cfg.HAROut = crawler.NewHARLog(f)
+	}
+
+	// Write the certificates of the HTTPS hosts if -tls-info is present, once across all targets
+	if *tlsInfo != "" {
+		f, err := os.Create(*tlsInfo)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not create TLS info file:", err)
+			exit(1)
+		}
+		defer f.Close()
+
+		var mu sync.Mutex
+		enc := json.NewEncoder(f)
+		seen := make(map[string]bool)
+
+		cfg.OnTLSInfo = func(info crawler.TLSInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if !seen[info.Host] {
+				seen[info.Host] = true
+				enc.Encode(info)
+			}
+		}
+	}
+
+	// the clock of -batch-maxtime starts with the run
+	limit := context.Background()
+	if *batchMaxTime > 0 {
+		var cancel context.CancelFunc
+		limit, cancel = context.WithTimeout(limit, *batchMaxTime)
+		defer cancel()
+	}
+
+	// Ctrl+C and SIGTERM end the run like -batch-maxtime, the results found so far are still written.
+	// Signals are handled once, a second Ctrl+C kills RockRawler
+	batch, stopSignals := signal.NotifyContext(limit, os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	go func() {
+		<-batch.Done()
+		stopSignals()
+	}()
+
+	// count the work of the crawls for the progress lines of -stats and for -metrics
+	progress, stopProgress := context.WithCancel(batch)
+	defer stopProgress()
+
+	if *stats || *metricsAddr != "" {
+		cfg.Metrics = crawler.NewMetrics()
+	}
+
+	if *stats && *statsInterval > 0 {
+		go reportProgress(progress, cfg.Metrics, *statsInterval)
+	}
+
+	if *metricsAddr != "" {
+		if err := serveMetrics(*metricsAddr, cfg.Metrics); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not serve the metrics:", err)
+			exit(1)
+		}
+	}
+
+	// the checks of -verify, -show-status, -live-only and -soft404 need the whole list of a target first
+	wholeList := cfg.Verify || cfg.ShowStatus || cfg.LiveOnly || cfg.DetectSoft404
+
+	// crawl -c targets at once, the output of a finished target is written in one go.
+	// URL lists are printed as they're found instead, unless something needs the whole list of a target first
+	stream := (format == formatPlain || format == formatCSV || format == formatBurp || format == formatPaths || format == formatHosts) &&
+		!*grouped && !*summary && !*estimate && !wholeList
+
+	type job struct {
+		url    string
+		target *crawler.Config
+
+		// set for the jobs of -serve, their results go to the API instead of the output
+		api *apiJob
+	}
+
+	jobs := make(chan job)
+	var outputMu sync.Mutex
+	var workers sync.WaitGroup
+
+	// deliver writes results of a target to the downloads list, the routed files and stdout.
+	// It returns all of them for -split, the routed ones too, but only the new ones with -unique-store
+	deliver := func(url string, results []crawler.Result) []crawler.Result {
+		if seenStore != nil {
+			results = seenStore.filter(results)
+		}
+		all := results
+
+		// route downloadable links into their own list
+		if downloadsList != nil {
+			var files []crawler.Result
+			results, files = splitDownloads(results)
+			downloadsList.write(url, files)
+		}
+
+		// and every type with its own file there
+		results = routeResults(url, results, routes)
+
+		stdout.write(url, results)
+
+		return all
+	}
+
+	if *concurrency < 1 {
+		*concurrency = 1
+	}
+
+	for i := 0; i < *concurrency; i++ {
+		workers.Add(1)
+
+		go func() {
+			defer workers.Done()
+
+			for j := range jobs {
+				url, target := j.url, j.target
+
+				if j.api != nil {
+					j.api.run(*maxTime, !wholeList)
+					continue
+				}
+
+				if *estimate {
+					links, requests := crawler.EstimateRequests(url, target)
+
+					outputMu.Lock()
+					fmt.Fprintf(stdout.w, "%s\t~%s requests (%d links to follow on the starting page, depth %d)\n", url, strconv.FormatFloat(requests, 'g', 3, 64), links, target.Depth)
+					outputMu.Unlock()
+					continue
+				}
+
+				// the clock of -max-time starts with the target
+				ctx, cancel := batch, context.CancelFunc(func() {})
+				if *maxTime > 0 {
+					ctx, cancel = context.WithTimeout(batch, *maxTime)
+				}
+
+				// the results of a streamed target are already written when the crawl ends
+				var streamed []crawler.Result
+				if stream {
+					target.OnResult = func(result crawler.Result) {
+						outputMu.Lock()
+						defer outputMu.Unlock()
+
+						streamed = append(streamed, deliver(url, []crawler.Result{result})...)
+					}
+				}
+
+				results, crawlStats := crawler.StartCrawlerStats(ctx, url, target)
+				cancel()
+
+				outputMu.Lock()
+
+				// sum the crawl up on stderr if -stats is present, stdout only has results
+				if *stats {
+					printStats(os.Stderr, crawlStats)
+				}
+
+				if *summary {
+					fmt.Fprintf(stdout.w, "%s\t%s\n", crawler.SummaryHash(results), url)
+					outputMu.Unlock()
+					continue
+				}
+
+				if stream {
+					results = streamed
+				} else {
+					results = deliver(url, results)
+				}
+
+				if split != nil {
+					if err := split.write(url, results); err != nil {
+						fmt.Fprintln(os.Stderr, "Could not write split output:", err)
+					}
+				}
+				outputMu.Unlock()
+			}
+		}()
+	}
+
+	// with -serve, the targets are POSTed to the API until the run is stopped instead of read from stdin
+	if *serve != "" {
+		submit := func(j *apiJob) {
+			select {
+			case jobs <- job{j.url, j.target, j}:
+			case <-j.ctx.Done():
+			}
+		}
+
+		if err := serveAPI(batch, *serve, cfg, *serveTTL, submit); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not serve the API:", err)
+			exit(1)
+		}
+	}
+
+	// get each line of stdin, push it to the work channel
+	s := bufio.NewScanner(os.Stdin)
+
+	for *serve == "" && s.Scan() {
+		if limit.Err() != nil {
+			fmt.Fprintln(os.Stderr, "Batch time limit reached, the remaining targets are skipped")
+			break
+		}
+
+		if batch.Err() != nil {
+			fmt.Fprintln(os.Stderr, "Interrupted, the remaining targets are skipped")
+			break
+		}
+
+		line := strings.TrimSpace(s.Text())
+
+		// skip blank lines and comments silently, and garbage with a warning
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// a line is a URL, optionally followed by flags for that target only
+		args, err := splitArgs(line)
+		if err != nil || len(args) == 0 || !crawler.IsTarget(args[0]) {
+			if cfg.Verbose {
+				fmt.Fprintln(os.Stderr, "Skipping invalid input:", line)
+			}
+			continue
+		}
+
+		url := args[0]
+
+		// -exact doesn't guess schemes
+		if *exact && !strings.Contains(url, "://") {
+			if cfg.Verbose {
+				fmt.Fprintln(os.Stderr, "Skipping input without a scheme:", line)
+			}
+			continue
+		}
+		target, err := inlineConfig(cfg, args[1:])
+		if err != nil {
+			// report mistyped flags, stay quiet about garbage
+			if strings.HasPrefix(args[1], "-") || cfg.Verbose {
+				fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", line, err)
+			}
+			continue
+		}
+
+		jobs <- job{url, target, nil}
+	}
+
+	close(jobs)
+	workers.Wait()
+
+	// sum the run up if -stats is present
+	stopProgress()
+	if *stats {
+		printTotals(os.Stderr, cfg.Metrics.Snapshot())
+	}
+
+	// Write the events still queued if -events-out is present
+	if cfg.Events != nil {
+		if dropped := cfg.Events.Close(); dropped > 0 {
+			fmt.Fprintln(os.Stderr, "Dropped", dropped, "events the writer couldn't keep up with")
+		}
+	}
+
+	// End the archive of -har-out
+	if cfg.HAROut != nil {
+		if err := cfg.HAROut.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not write HAR file:", err)
+		}
+	}
+
+	// Keep the cookies for the next run
+	if cfg.Cookies != nil {
+		if err := cfg.Cookies.Save(); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not save cookies:", err)
+		}
+	}
+
+	// Keep the URLs written for the next run
+	if seenStore != nil {
+		if err := seenStore.save(); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not save unique store:", err)
+		}
+	}
+
+	// Keep the validators for the next run
+	if cfg.Validators != nil {
+		if err := cfg.Validators.Save(); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not save validators:", err)
+		}
+	}
+
+	// Dump the heap if -memprofile is present
+	if *memProfile != "" {
+		if err := writeHeapProfile(*memProfile); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not write memory profile:", err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/abdallah-elsharif/RockRawler/crawler"
+)
+
+// reportProgress writes a progress line of -stats to stderr every interval until ctx is done
+func reportProgress(ctx context.Context, metrics *crawler.Metrics, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := metrics.Snapshot()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		s := metrics.Snapshot()
+		rate := float64(s.Requests-last.Requests) / (s.Elapsed - last.Elapsed).Seconds()
+		last = s
+
+		printProgress(os.Stderr, s, rate)
+	}
+}
+
+// serveMetrics serves the metrics in the Prometheus format on addr at /metrics, until the run ends
+func serveMetrics(addr string, metrics *crawler.Metrics) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		metrics.WritePrometheus(w)
+	})
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "Serving the metrics on http://"+listener.Addr().String()+"/metrics")
+
+	go func() {
+		if err := http.Serve(listener, mux); !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintln(os.Stderr, "Metrics server stopped:", err)
+		}
+	}()
+
+	return nil
+}
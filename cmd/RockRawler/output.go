@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abdallah-elsharif/RockRawler/crawler"
+)
+
+// output formats
+const (
+	formatPlain = "plain"
+	formatJSON  = "json"
+	formatBurp  = "burp"
+	formatCount = "count"
+	formatPaths = "paths"
+	formatCSV   = "csv"
+	formatHosts = "hosts"
+)
+
+// output writes the results of every crawled target to w
+type output struct {
+	w      io.Writer
+	format string
+
+	// URLs (or paths, or hosts) written so far, burp, paths and hosts lists are unique across targets
+	seen map[string]bool
+
+	// paths lists leave the query out
+	ignoreQuery bool
+
+	// with -grouped, the results of each target follow a header naming it
+	grouped bool
+
+	// whether the CSV header row was written
+	csvHeader bool
+
+	// plain lists start each URL with the page it was found on and its type, for -where
+	where bool
+}
+
+func newOutput(w io.Writer, format string) *output {
+	return &output{w: w, format: format, seen: make(map[string]bool)}
+}
+
+// write prints the results of a target in the output's format
+func (o *output) write(target string, results []crawler.Result) {
+	if o.grouped && len(results) > 0 {
+		o.header(target)
+	}
+
+	switch o.format {
+	case formatJSON:
+		printResultsJSON(o.w, results)
+	case formatCSV:
+		printResultsCSV(o.w, results, !o.csvHeader)
+		o.csvHeader = true
+	case formatBurp:
+		printResults(o.w, o.burpResults(results))
+	case formatCount:
+		printCounts(o.w, results)
+	case formatPaths:
+		for _, path := range o.paths(results) {
+			fmt.Fprintln(o.w, path)
+		}
+	case formatHosts:
+		for _, host := range o.hosts(results) {
+			fmt.Fprintln(o.w, host)
+		}
+	default:
+		if o.where {
+			printWhere(o.w, results)
+		} else {
+			printResults(o.w, results)
+		}
+	}
+}
+
+// header starts the block of a target, a comment line that RockRawler skips when
+// the output is fed back as input, or a {"target": ...} object in JSON lines
+func (o *output) header(target string) {
+	if o.format == formatJSON {
+		enc := json.NewEncoder(o.w)
+		enc.SetEscapeHTML(false)
+		enc.Encode(struct {
+			Target string `json:"target"`
+		}{target})
+		return
+	}
+
+	fmt.Fprintf(o.w, "# %s\n", target)
+}
+
+// splitOutput writes the results of every crawled host to its own file in a directory, for -split.
+// Files are only open while a target's results are appended, batches can have thousands of hosts
+type splitOutput struct {
+	dir  string
+	open func(w io.Writer) *output
+
+	// host => its output, writing into buf
+	hosts map[string]*output
+	buf   bytes.Buffer
+}
+
+func newSplitOutput(dir string, open func(w io.Writer) *output) *splitOutput {
+	return &splitOutput{dir: dir, open: open, hosts: make(map[string]*output)}
+}
+
+// write appends the results of a target to the file of its host, the first write of the run truncates it
+func (s *splitOutput) write(target string, results []crawler.Result) error {
+	host := crawler.TargetHost(target)
+	o, ok := s.hosts[host]
+
+	if !ok {
+		o = s.open(&s.buf)
+		s.hosts[host] = o
+	}
+
+	s.buf.Reset()
+	o.write(target, results)
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if !ok {
+		flags |= os.O_TRUNC
+	}
+
+	ext := ".txt"
+	if o.format == formatJSON {
+		ext = ".json"
+	} else if o.format == formatCSV {
+		ext = ".csv"
+	}
+
+	f, err := os.OpenFile(filepath.Join(s.dir, crawler.SafeFilename(host)+ext), flags, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(s.buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// routeResults writes the results whose type has its own output there and returns the others
+func routeResults(target string, results []crawler.Result, routes map[string]*output) []crawler.Result {
+	if len(routes) == 0 {
+		return results
+	}
+
+	rest := make([]crawler.Result, 0, len(results))
+	routed := make(map[*output][]crawler.Result)
+
+	for _, result := range results {
+		if o, ok := routes[result.Type]; ok {
+			routed[o] = append(routed[o], result)
+		} else {
+			rest = append(rest, result)
+		}
+	}
+
+	for o, list := range routed {
+		o.write(target, list)
+	}
+
+	return rest
+}
+
+// burpResults keeps the absolute http(s) URLs that weren't written yet,
+// Burp can't seed its site map or a scan with mailto:, javascript: and similar links
+func (o *output) burpResults(results []crawler.Result) []crawler.Result {
+	kept := make([]crawler.Result, 0, len(results))
+
+	for _, result := range results {
+		if !crawler.IsWebURL(result.URL) {
+			continue
+		}
+
+		if !o.seen[result.URL] {
+			o.seen[result.URL] = true
+			kept = append(kept, result)
+		}
+	}
+
+	return kept
+}
+
+// paths returns the path and query of the http(s) URLs, dropping the host, that weren't written yet.
+// Tenants of the same application on different hosts share their routes
+func (o *output) paths(results []crawler.Result) []string {
+	paths := make([]string, 0, len(results))
+
+	for _, result := range results {
+		if !crawler.IsWebURL(result.URL) {
+			continue
+		}
+
+		u, _ := url.Parse(result.URL)
+		path := u.EscapedPath()
+
+		if path == "" {
+			path = "/"
+		}
+
+		if u.RawQuery != "" && !o.ignoreQuery {
+			path += "?" + u.RawQuery
+		}
+
+		if !o.seen[path] {
+			o.seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+
+	return paths
+}
+
+// hosts returns the hostnames of the http(s) URLs that weren't written yet, lowercase and without the port,
+// ready to be resolved
+func (o *output) hosts(results []crawler.Result) []string {
+	hosts := make([]string, 0)
+
+	for _, result := range results {
+		if !crawler.IsWebURL(result.URL) {
+			continue
+		}
+
+		u, _ := url.Parse(result.URL)
+		host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+
+		if host != "" && !o.seen[host] {
+			o.seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+
+	return hosts
+}
+
+func printResults(w io.Writer, results []crawler.Result) {
+	for _, res := range results {
+		fmt.Fprintf(w, "%s\n", res.URL)
+	}
+}
+
+// printWhere writes "source\ttype\turl" lines, - stands for a missing source
+func printWhere(w io.Writer, results []crawler.Result) {
+	for _, res := range results {
+		source := res.Source
+		if source == "" {
+			source = "-"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\n", source, res.Type, res.URL)
+	}
+}
+
+// printCounts writes "count\turl" lines, the most referenced URLs first
+func printCounts(w io.Writer, results []crawler.Result) {
+	sorted := append([]crawler.Result(nil), results...)
+
+	// ties keep the discovery order
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Count > sorted[j].Count
+	})
+
+	for _, res := range sorted {
+		fmt.Fprintf(w, "%d\t%s\n", res.Count, res.URL)
+	}
+}
+
+// printResultsJSON writes one JSON object per result
+func printResultsJSON(w io.Writer, results []crawler.Result) {
+	enc := json.NewEncoder(w)
+
+	// keep & < > readable in URLs, they are still valid JSON
+	enc.SetEscapeHTML(false)
+
+	for _, res := range results {
+		enc.Encode(res)
+	}
+}
+
+// printResultsCSV writes a row per result, after the header row if header is set
+func printResultsCSV(w io.Writer, results []crawler.Result, header bool) {
+	cw := csv.NewWriter(w)
+
+	if header {
+		cw.Write([]string{"url", "source", "depth", "type", "status"})
+	}
+
+	for _, res := range results {
+		status := ""
+		if res.Status != 0 {
+			status = strconv.Itoa(res.Status)
+		}
+
+		cw.Write([]string{res.URL, res.Source, strconv.Itoa(res.Depth), res.Type, status})
+	}
+
+	cw.Flush()
+}
+
+// printStats writes the one-line summary of a crawl for -stats
+func printStats(w io.Writer, stats crawler.Stats) {
+	fmt.Fprintf(w, "%s: %d URLs, %d requests, %d errors in %s\n", stats.Host, stats.URLs, stats.Requests, stats.Errors, stats.Elapsed.Round(time.Millisecond))
+}
+
+// printProgress writes a progress line of -stats, the rate is the one since the previous line
+func printProgress(w io.Writer, s crawler.MetricsSnapshot, rate float64) {
+	fmt.Fprintf(w, "Progress: %d running, %d done, %d requests (%.1f/s), %d in flight, %d queued, %d URLs, %s, statuses %s\n",
+		s.Running, s.Done, s.Requests, rate, s.InFlight, s.Queued, s.URLs, formatErrors(s), formatStatuses(s))
+}
+
+// printTotals writes the summary of the whole run for -stats
+func printTotals(w io.Writer, s crawler.MetricsSnapshot) {
+	rate := 0.0
+	if seconds := s.Elapsed.Seconds(); seconds > 0 {
+		rate = float64(s.Requests) / seconds
+	}
+
+	fmt.Fprintf(w, "Total: %d targets, %d URLs, %d requests (%.1f/s), %s, statuses %s in %s\n",
+		s.Done, s.URLs, s.Requests, rate, formatErrors(s), formatStatuses(s), s.Elapsed.Round(time.Millisecond))
+}
+
+// formatErrors writes "5 errors (dns 2, timeout 3)"
+func formatErrors(s crawler.MetricsSnapshot) string {
+	if s.Errors == 0 {
+		return "0 errors"
+	}
+
+	kinds := make([]string, 0, len(s.ErrorTypes))
+	for kind := range s.ErrorTypes {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	parts := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		parts = append(parts, fmt.Sprintf("%s %d", kind, s.ErrorTypes[kind]))
+	}
+
+	return fmt.Sprintf("%d errors (%s)", s.Errors, strings.Join(parts, ", "))
+}
+
+// formatStatuses writes "200: 1100, 404: 12", "none" before the first response
+func formatStatuses(s crawler.MetricsSnapshot) string {
+	statuses := make([]int, 0, len(s.Statuses))
+	for status := range s.Statuses {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+
+	parts := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		parts = append(parts, fmt.Sprintf("%d: %d", status, s.Statuses[status]))
+	}
+
+	if len(parts) == 0 {
+		return "none"
+	}
+
+	return strings.Join(parts, ", ")
+}
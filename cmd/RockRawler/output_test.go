@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/abdallah-elsharif/RockRawler/crawler"
+)
+
+func TestRouteResults(t *testing.T) {
+	results := []crawler.Result{
+		{URL: "https://x.com/a", Type: "href"},
+		{URL: "https://x.com/app.js", Type: "script"},
+		{URL: "https://x.com/next", Type: "link-header"},
+		{URL: "https://x.com/login", Type: "form"},
+	}
+
+	tests := []struct {
+		name   string
+		routes map[string]string
+		files  map[string]string
+		rest   []string
+	}{
+		{
+			name: "no routes",
+			rest: []string{"https://x.com/a", "https://x.com/app.js", "https://x.com/next", "https://x.com/login"},
+		},
+		{
+			name:   "types sharing a file",
+			routes: map[string]string{"href": "links", "link-header": "links"},
+			files:  map[string]string{"links": "https://x.com/a\nhttps://x.com/next\n"},
+			rest:   []string{"https://x.com/app.js", "https://x.com/login"},
+		},
+		{
+			name:   "a file per type",
+			routes: map[string]string{"href": "links", "script": "scripts", "form": "forms"},
+			files:  map[string]string{"links": "https://x.com/a\n", "scripts": "https://x.com/app.js\n", "forms": "https://x.com/login\n"},
+			rest:   []string{"https://x.com/next"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buffers := make(map[string]*bytes.Buffer)
+			byFile := make(map[string]*output)
+			routes := make(map[string]*output)
+
+			for kind, file := range tt.routes {
+				o, ok := byFile[file]
+				if !ok {
+					buffers[file] = new(bytes.Buffer)
+					o = newOutput(buffers[file], formatPlain)
+					byFile[file] = o
+				}
+				routes[kind] = o
+			}
+
+			if rest := urlsOf(routeResults("https://x.com", results, routes)); !reflect.DeepEqual(rest, tt.rest) {
+				t.Errorf("rest = %v, want %v", rest, tt.rest)
+			}
+
+			for file, buf := range buffers {
+				if buf.String() != tt.files[file] {
+					t.Errorf("%s = %q, want %q", file, buf.String(), tt.files[file])
+				}
+			}
+		})
+	}
+}
+
+func TestPrintCounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []crawler.Result
+		want    string
+	}{
+		{"nothing", nil, ""},
+		{"most referenced first", []crawler.Result{{URL: "a", Count: 1}, {URL: "b", Count: 3}, {URL: "c", Count: 2}}, "3\tb\n2\tc\n1\ta\n"},
+		{"ties in discovery order", []crawler.Result{{URL: "a", Count: 2}, {URL: "b", Count: 1}, {URL: "c", Count: 2}}, "2\ta\n2\tc\n1\tb\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			printCounts(&buf, tt.results)
+
+			if buf.String() != tt.want {
+				t.Errorf("printCounts = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestPaths(t *testing.T) {
+	results := []crawler.Result{
+		{URL: "https://a.x.com/users?id=1"},
+		{URL: "https://b.x.com/users?id=1"},
+		{URL: "https://b.x.com"},
+		{URL: "mailto:me@x.com"},
+		{URL: "https://a.x.com/users?id=2"},
+		{URL: "http://c.x.com/a%20b"},
+	}
+
+	tests := []struct {
+		name        string
+		ignoreQuery bool
+		want        []string
+	}{
+		{"with the queries", false, []string{"/users?id=1", "/", "/users?id=2", "/a%20b"}},
+		{"without the queries", true, []string{"/users", "/", "/a%20b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newOutput(new(bytes.Buffer), formatPaths)
+			o.ignoreQuery = tt.ignoreQuery
+
+			if got := o.paths(results); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("paths = %v, want %v", got, tt.want)
+			}
+
+			// the paths are unique across targets
+			if got := o.paths(results); len(got) != 0 {
+				t.Errorf("paths of the next target = %v, want none", got)
+			}
+		})
+	}
+}
+
+func TestGrouped(t *testing.T) {
+	results := []crawler.Result{{URL: "https://x.com/a", Count: 2}, {URL: "https://x.com/b", Count: 1}}
+
+	tests := []struct {
+		name    string
+		format  string
+		grouped bool
+		want    string
+	}{
+		{"plain", formatPlain, false, "https://x.com/a\nhttps://x.com/b\n"},
+		{"plain grouped", formatPlain, true, "# https://x.com\nhttps://x.com/a\nhttps://x.com/b\n"},
+		{"count grouped", formatCount, true, "# https://x.com\n2\thttps://x.com/a\n1\thttps://x.com/b\n"},
+		{"paths grouped", formatPaths, true, "# https://x.com\n/a\n/b\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			o := newOutput(&buf, tt.format)
+			o.grouped = tt.grouped
+
+			o.write("https://x.com", results)
+
+			// targets without results get no header
+			o.write("https://y.com", nil)
+
+			if buf.String() != tt.want {
+				t.Errorf("output = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func urlsOf(results []crawler.Result) []string {
+	urls := make([]string, 0, len(results))
+	for _, result := range results {
+		urls = append(urls, result.URL)
+	}
+
+	return urls
+}
@@ -0,0 +1,521 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abdallah-elsharif/RockRawler/crawler"
+)
+
+const (
+	// maxEndedJobs is how many jobs that ended -serve keeps at most, the oldest are forgotten first
+	maxEndedJobs = 1000
+
+	// maxActiveJobs is how many jobs can be queued or running at once, the API answers 503 past it
+	maxActiveJobs = 1000
+
+	// maxJobBody is the size of a job's JSON at most
+	maxJobBody = 1 << 20
+)
+
+// apiJob is a crawl submitted to -serve, it waits for a free worker like the targets of stdin
+type apiJob struct {
+	id     string
+	url    string
+	target *crawler.Config
+
+	// cancelled by DELETE and when the run stops
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	status   string
+	started  time.Time
+	finished time.Time
+	results  []crawler.Result
+
+	// closed and replaced whenever results come in or the status changes, it wakes up the streams
+	changed chan struct{}
+}
+
+// jobState is the JSON of a job, its status is queued, running, done or cancelled
+type jobState struct {
+	ID       string     `json:"id"`
+	URL      string     `json:"url"`
+	Status   string     `json:"status"`
+	Results  int        `json:"results"`
+	Started  *time.Time `json:"started,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+}
+
+// run crawls the target of the job on a worker, -max-time bounds it like a target of stdin.
+// Streamed results come in as they're found, the others at the end of the crawl
+func (j *apiJob) run(maxTime time.Duration, stream bool) {
+	ctx, cancel := j.ctx, context.CancelFunc(func() {})
+	if maxTime > 0 {
+		ctx, cancel = context.WithTimeout(j.ctx, maxTime)
+	}
+	defer cancel()
+	defer j.cancel()
+
+	if !j.start() {
+		return
+	}
+
+	if stream {
+		j.target.OnResult = func(result crawler.Result) {
+			j.add(result)
+		}
+	}
+
+	results := crawler.StartCrawlerContext(ctx, j.url, j.target)
+
+	if !stream {
+		j.add(results...)
+	}
+
+	j.finish()
+}
+
+// start marks the job running, unless it was cancelled while queued
+func (j *apiJob) start() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.status != "queued" {
+		return false
+	}
+
+	j.status = "running"
+	j.started = time.Now()
+	j.notify()
+
+	return true
+}
+
+// add appends results of the crawl
+func (j *apiJob) add(results ...crawler.Result) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.results = append(j.results, results...)
+	j.notify()
+}
+
+// finish ends the job, cancelled when its context was
+func (j *apiJob) finish() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.end()
+}
+
+// abandon cancels a job that no worker took yet, once its context is done
+func (j *apiJob) abandon() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.status == "queued" && j.ctx.Err() != nil {
+		j.end()
+	}
+}
+
+// end sets the final status, j.mu is held
+func (j *apiJob) end() {
+	if j.status == "done" || j.status == "cancelled" {
+		return
+	}
+
+	j.status = "done"
+	if j.ctx.Err() != nil {
+		j.status = "cancelled"
+	}
+
+	j.finished = time.Now()
+	j.notify()
+}
+
+// notify wakes up the streams of the job, j.mu is held
+func (j *apiJob) notify() {
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// ended returns when the job ended, zero while it's queued or running
+func (j *apiJob) ended() time.Time {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.finished
+}
+
+// since returns the results from the offset on, the channel closed on the next change and whether the job ended
+func (j *apiJob) since(offset int) ([]crawler.Result, <-chan struct{}, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	ended := j.status == "done" || j.status == "cancelled"
+
+	return j.results[offset:], j.changed, ended
+}
+
+func (j *apiJob) state() jobState {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	state := jobState{ID: j.id, URL: j.url, Status: j.status, Results: len(j.results)}
+
+	if !j.started.IsZero() {
+		started := j.started
+		state.Started = &started
+	}
+
+	if !j.finished.IsZero() {
+		finished := j.finished
+		state.Finished = &finished
+	}
+
+	return state
+}
+
+// apiServer takes crawl jobs over HTTP for -serve. The global flags are the defaults of every job,
+// a job can set the flags of an input line on top of them
+type apiServer struct {
+	ctx context.Context
+	cfg *crawler.Config
+
+	// how long the jobs that ended are kept, 0 keeps them until the run stops
+	ttl time.Duration
+
+	// hands a job to the workers, it returns once a worker took it or the job was cancelled
+	submit func(j *apiJob)
+
+	mu   sync.Mutex
+	next int
+	jobs map[string]*apiJob
+
+	// jobs waiting for a worker, the workers stop once they're all handed over
+	pending sync.WaitGroup
+}
+
+// serveAPI serves the job API on addr until ctx is done, the jobs still running are cancelled then.
+// The jobs that ended are forgotten ttl after, and past maxEndedJobs
+func serveAPI(ctx context.Context, addr string, cfg *crawler.Config, ttl time.Duration, submit func(j *apiJob)) error {
+	s := &apiServer{ctx: ctx, cfg: cfg, ttl: ttl, submit: submit, jobs: make(map[string]*apiJob)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", s.create)
+	mux.HandleFunc("GET /jobs", s.list)
+	mux.HandleFunc("GET /jobs/{id}", s.get)
+	mux.HandleFunc("GET /jobs/{id}/results", s.stream)
+	mux.HandleFunc("DELETE /jobs/{id}", s.delete)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "Serving the crawl API on http://"+listener.Addr().String())
+
+	srv := &http.Server{Handler: mux}
+	stopped := make(chan struct{})
+
+	if ttl > 0 {
+		go s.expire(ctx)
+	}
+
+	go func() {
+		defer close(stopped)
+
+		<-ctx.Done()
+
+		// the streams end with their jobs, which ctx cancels
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := srv.Shutdown(shutdown); err != nil {
+			srv.Close()
+		}
+	}()
+
+	if err := srv.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	// no job is created once the handlers are done
+	<-stopped
+	s.pending.Wait()
+
+	return nil
+}
+
+// create queues the crawl of {"url": "https://example.com", "flags": "-d 3 -subs"}
+func (s *apiServer) create(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URL   string `json:"url"`
+		Flags string `json:"flags"`
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxJobBody)
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			apiError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("the job is larger than %d bytes", maxJobBody))
+			return
+		}
+
+		apiError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+
+	// the same checks as the input lines
+	if !crawler.IsTarget(req.URL) {
+		apiError(w, http.StatusBadRequest, "invalid url")
+		return
+	}
+
+	if s.cfg.Exact && !strings.Contains(req.URL, "://") {
+		apiError(w, http.StatusBadRequest, "-exact needs a url with a scheme")
+		return
+	}
+
+	args, err := splitArgs(req.Flags)
+	if err != nil {
+		apiError(w, http.StatusBadRequest, "invalid flags: "+err.Error())
+		return
+	}
+
+	target, err := inlineConfig(s.cfg, args)
+	if err != nil {
+		apiError(w, http.StatusBadRequest, "invalid flags: "+err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	if s.active() >= maxActiveJobs {
+		s.mu.Unlock()
+		apiError(w, http.StatusServiceUnavailable, fmt.Sprintf("%d jobs are already queued or running", maxActiveJobs))
+		return
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+
+	s.next++
+	j := &apiJob{
+		id:      strconv.Itoa(s.next),
+		url:     req.URL,
+		target:  target,
+		ctx:     ctx,
+		cancel:  cancel,
+		status:  "queued",
+		changed: make(chan struct{}),
+	}
+	s.jobs[j.id] = j
+	s.evict()
+	s.mu.Unlock()
+
+	s.pending.Add(1)
+
+	go func() {
+		defer s.pending.Done()
+
+		s.submit(j)
+		j.abandon()
+	}()
+
+	apiJSON(w, http.StatusCreated, j.state())
+}
+
+// list returns the state of every job, in the order they were created
+func (s *apiServer) list(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	states := make([]jobState, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		states = append(states, j.state())
+	}
+	s.mu.Unlock()
+
+	sort.Slice(states, func(a, b int) bool {
+		idA, _ := strconv.Atoi(states[a].ID)
+		idB, _ := strconv.Atoi(states[b].ID)
+		return idA < idB
+	})
+
+	apiJSON(w, http.StatusOK, states)
+}
+
+func (s *apiServer) get(w http.ResponseWriter, r *http.Request) {
+	if j := s.job(w, r); j != nil {
+		apiJSON(w, http.StatusOK, j.state())
+	}
+}
+
+// stream sends the results of a job as server-sent events, the ones found so far first.
+// A done event with the state of the job closes the stream once the crawl ended
+func (s *apiServer) stream(w http.ResponseWriter, r *http.Request) {
+	j := s.job(w, r)
+	if j == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	sent := 0
+
+	for {
+		results, changed, ended := j.since(sent)
+
+		// Encode ends the data line, the blank line ends the event
+		for _, result := range results {
+			fmt.Fprint(w, "data: ")
+			enc.Encode(result)
+			fmt.Fprint(w, "\n")
+		}
+		sent += len(results)
+
+		if ended {
+			fmt.Fprint(w, "event: done\ndata: ")
+			enc.Encode(j.state())
+			fmt.Fprint(w, "\n")
+		}
+
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if ended {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// delete cancels a job and forgets it, the results found so far are lost
+func (s *apiServer) delete(w http.ResponseWriter, r *http.Request) {
+	j := s.job(w, r)
+	if j == nil {
+		return
+	}
+
+	s.mu.Lock()
+	delete(s.jobs, j.id)
+	s.mu.Unlock()
+
+	j.cancel()
+	j.abandon()
+
+	apiJSON(w, http.StatusOK, j.state())
+}
+
+// expire forgets the jobs that ended more than s.ttl ago until ctx is done
+func (s *apiServer) expire(ctx context.Context) {
+	ticker := time.NewTicker(min(max(s.ttl/2, time.Second), time.Minute))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			s.evict()
+			s.mu.Unlock()
+		}
+	}
+}
+
+// active returns how many jobs are queued or running, s.mu is held
+func (s *apiServer) active() int {
+	active := 0
+
+	for _, j := range s.jobs {
+		if j.ended().IsZero() {
+			active++
+		}
+	}
+
+	return active
+}
+
+// evict forgets the jobs that ended more than s.ttl ago, then the oldest ones past maxEndedJobs, s.mu is held
+func (s *apiServer) evict() {
+	type endedJob struct {
+		id       string
+		finished time.Time
+	}
+
+	var ended []endedJob
+
+	for id, j := range s.jobs {
+		finished := j.ended()
+
+		switch {
+		case finished.IsZero():
+		case s.ttl > 0 && time.Since(finished) > s.ttl:
+			delete(s.jobs, id)
+		default:
+			ended = append(ended, endedJob{id, finished})
+		}
+	}
+
+	if len(ended) <= maxEndedJobs {
+		return
+	}
+
+	sort.Slice(ended, func(a, b int) bool {
+		return ended[a].finished.Before(ended[b].finished)
+	})
+
+	for _, j := range ended[:len(ended)-maxEndedJobs] {
+		delete(s.jobs, j.id)
+	}
+}
+
+// job returns the job of the request's {id}, or answers 404
+func (s *apiServer) job(w http.ResponseWriter, r *http.Request) *apiJob {
+	s.mu.Lock()
+	j := s.jobs[r.PathValue("id")]
+	s.mu.Unlock()
+
+	if j == nil {
+		apiError(w, http.StatusNotFound, "no such job")
+	}
+
+	return j
+}
+
+func apiJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+}
+
+func apiError(w http.ResponseWriter, status int, message string) {
+	apiJSON(w, status, struct {
+		Error string `json:"error"`
+	}{message})
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abdallah-elsharif/RockRawler/crawler"
+)
+
+func TestEvictJobs(t *testing.T) {
+	s := &apiServer{ttl: time.Hour, jobs: make(map[string]*apiJob)}
+
+	add := func(id string, status string, finished time.Time) {
+		s.jobs[id] = &apiJob{id: id, ctx: context.Background(), status: status, finished: finished, changed: make(chan struct{})}
+	}
+
+	add("running", "running", time.Time{})
+	add("expired", "done", time.Now().Add(-2*time.Hour))
+	add("kept", "cancelled", time.Now().Add(-time.Minute))
+
+	s.evict()
+
+	for id, want := range map[string]bool{"running": true, "expired": false, "kept": true} {
+		if _, ok := s.jobs[id]; ok != want {
+			t.Errorf("job %s kept: %v, want %v", id, ok, want)
+		}
+	}
+
+	// past the cap, the jobs that ended first go
+	for i := 0; i < maxEndedJobs+5; i++ {
+		add(strconv.Itoa(i), "done", time.Now().Add(time.Duration(i)*time.Second))
+	}
+
+	s.evict()
+
+	if len(s.jobs) != maxEndedJobs+1 {
+		t.Errorf("%d jobs kept, want %d ended ones and the running one", len(s.jobs), maxEndedJobs)
+	}
+
+	for _, id := range []string{"running", strconv.Itoa(maxEndedJobs + 4)} {
+		if _, ok := s.jobs[id]; !ok {
+			t.Errorf("job %s was forgotten", id)
+		}
+	}
+
+	if _, ok := s.jobs["kept"]; ok {
+		t.Error("the oldest job that ended was kept past the cap")
+	}
+}
+
+func TestCreateLimits(t *testing.T) {
+	s := &apiServer{ctx: context.Background(), cfg: &crawler.Config{DefaultScheme: "http"}, submit: func(j *apiJob) {}, jobs: make(map[string]*apiJob)}
+
+	post := func(body string) int {
+		w := httptest.NewRecorder()
+		s.create(w, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(body)))
+		s.pending.Wait()
+
+		return w.Code
+	}
+
+	if code := post(`{"url": "https://example.com", "flags": "` + strings.Repeat("a", maxJobBody) + `"}`); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("a job larger than %d bytes answered %d, want 413", maxJobBody, code)
+	}
+
+	for i := 0; i < maxActiveJobs; i++ {
+		if code := post(`{"url": "https://example.com"}`); code != http.StatusCreated {
+			t.Fatalf("job %d answered %d, want 201", i, code)
+		}
+	}
+
+	if code := post(`{"url": "https://example.com"}`); code != http.StatusServiceUnavailable {
+		t.Errorf("a job past %d queued ones answered %d, want 503", maxActiveJobs, code)
+	}
+
+	// a job that ended frees its place
+	s.jobs["1"].finish()
+
+	if code := post(`{"url": "https://example.com"}`); code != http.StatusCreated {
+		t.Errorf("a job after one ended answered %d, want 201", code)
+	}
+}
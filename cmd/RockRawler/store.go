@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abdallah-elsharif/RockRawler/crawler"
+)
+
+// uniqueStore is the -unique-store file, a sorted list of the URLs the previous runs wrote with the time
+// each was first written, "URL<tab>RFC 3339 time" (the lines of stores without times have the URL alone).
+// Only the URLs it doesn't have yet are written, they're added to it at the end of the run
+type uniqueStore struct {
+	path string
+
+	// with -since, the URLs first seen after it are written too
+	since time.Time
+
+	mu    sync.Mutex
+	seen  map[string]time.Time
+	added int
+
+	// URLs written by this run, with -since
+	written map[string]bool
+}
+
+// loadUniqueStore reads the store at path, a missing file is an empty store
+func loadUniqueStore(path string) (*uniqueStore, error) {
+	s := &uniqueStore{path: path, seen: make(map[string]time.Time), written: make(map[string]bool)}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		link, seen, _ := strings.Cut(strings.TrimSpace(scanner.Text()), "\t")
+		if link == "" {
+			continue
+		}
+
+		// the URLs without a time count as seen long ago
+		first, _ := time.Parse(time.RFC3339, seen)
+		s.seen[link] = first
+	}
+
+	return s, scanner.Err()
+}
+
+// filter returns the results whose URL isn't in the store (or was first seen after -since) and adds them to it
+func (s *uniqueStore) filter(results []crawler.Result) []crawler.Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fresh := make([]crawler.Result, 0, len(results))
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for _, result := range results {
+		first, ok := s.seen[result.URL]
+
+		if !ok {
+			s.seen[result.URL] = now
+			s.added++
+		} else if s.since.IsZero() || !first.After(s.since) || s.written[result.URL] {
+			continue
+		}
+
+		if !s.since.IsZero() {
+			s.written[result.URL] = true
+		}
+
+		fresh = append(fresh, result)
+	}
+
+	return fresh
+}
+
+// save writes the store back sorted, when the run added URLs
+func (s *uniqueStore) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.added == 0 {
+		return nil
+	}
+
+	links := make([]string, 0, len(s.seen))
+	for link := range s.seen {
+		links = append(links, link)
+	}
+	sort.Strings(links)
+
+	f, err := os.Create(s.path)
+
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	for _, link := range links {
+		if first := s.seen[link]; first.IsZero() {
+			w.WriteString(link + "\n")
+		} else {
+			w.WriteString(link + "\t" + first.Format(time.RFC3339) + "\n")
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// parseSince parses -since, a time (RFC 3339 or a date) or a duration back from now
+func parseSince(value string) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("%q is neither a time, a date nor a duration", value)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abdallah-elsharif/RockRawler/crawler"
+)
+
+func TestParseSince(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+		err   bool
+	}{
+		{"2024-05-01T10:00:00Z", time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), false},
+		{"2024-05-01", time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local), false},
+		{"yesterday", time.Time{}, true},
+		{"2024-13-01", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSince(tt.value)
+
+		if (err != nil) != tt.err || !got.Equal(tt.want) {
+			t.Errorf("parseSince(%q) = %v, %v, want %v (error: %v)", tt.value, got, err, tt.want, tt.err)
+		}
+	}
+
+	got, err := parseSince("24h")
+	if err != nil || time.Since(got) < 24*time.Hour || time.Since(got) > 25*time.Hour {
+		t.Errorf("parseSince(\"24h\") = %v, %v, want a day ago", got, err)
+	}
+}
+
+func TestUniqueStoreSince(t *testing.T) {
+	now := time.Now().UTC()
+	recent := now.Add(-time.Hour).Format(time.RFC3339)
+	old := now.Add(-72 * time.Hour).Format(time.RFC3339)
+
+	results := []crawler.Result{
+		{URL: "https://x.com/new"},
+		{URL: "https://x.com/recent"},
+		{URL: "https://x.com/old"},
+		{URL: "https://x.com/untimed"},
+	}
+
+	tests := []struct {
+		name  string
+		since time.Duration
+		want  []string
+	}{
+		{"without -since", 0, []string{"https://x.com/new"}},
+		{"since a day", 24 * time.Hour, []string{"https://x.com/new", "https://x.com/recent"}},
+		{"since a week", 7 * 24 * time.Hour, []string{"https://x.com/new", "https://x.com/recent", "https://x.com/old"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "seen.txt")
+			lines := "https://x.com/old\t" + old + "\nhttps://x.com/recent\t" + recent + "\nhttps://x.com/untimed\n"
+
+			if err := os.WriteFile(path, []byte(lines), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			s, err := loadUniqueStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.since > 0 {
+				s.since = now.Add(-tt.since)
+			}
+
+			if got := urlsOf(s.filter(results)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filter = %v, want %v", got, tt.want)
+			}
+
+			// a URL is written once per run
+			if got := s.filter(results); len(got) != 0 {
+				t.Errorf("second filter = %v, want nothing", urlsOf(got))
+			}
+
+			if err := s.save(); err != nil {
+				t.Fatal(err)
+			}
+
+			data, _ := os.ReadFile(path)
+			saved := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+			if len(saved) != 4 || !strings.HasPrefix(saved[0], "https://x.com/new\t") || saved[3] != "https://x.com/untimed" {
+				t.Errorf("saved store %q, want the new URL with its time and the others as they were", saved)
+			}
+		})
+	}
+}
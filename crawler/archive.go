@@ -0,0 +1,280 @@
+package crawler
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxArchiveURLs bounds the URLs taken from each archive, popular hosts have millions of captures
+const maxArchiveURLs = 10000
+
+// archiveTimeout bounds a request to an archive, their indexes are slow on big hosts
+const archiveTimeout = time.Minute
+
+// errNoCaptures is the 404 of an archive that never saw the host, it's no failure
+var errNoCaptures = errors.New("no captures")
+
+// The archive APIs, variables so they can be pointed elsewhere at build time
+var (
+	waybackAPI       = "https://web.archive.org/cdx/search/cdx"
+	otxAPI           = "https://otx.alienvault.com/api/v1/indicators"
+	commonCrawlIndex = "https://index.commoncrawl.org/collinfo.json"
+)
+
+// archiveSources lists the archives -archive knows, in the order they're queried
+var archiveSources = []string{"wayback", "otx", "commoncrawl"}
+
+// ParseArchives parses a comma-separated list of archives, "all" stands for every one of them
+func ParseArchives(list string) ([]string, error) {
+	archives := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, field := range strings.Split(list, ",") {
+		name := strings.ToLower(strings.TrimSpace(field))
+
+		if name == "all" {
+			return archiveSources, nil
+		}
+
+		known := false
+		for _, source := range archiveSources {
+			known = known || source == name
+		}
+
+		if !known {
+			return nil, fmt.Errorf("unknown archive %q (expected %s or all)", field, strings.Join(archiveSources, ", "))
+		}
+
+		if !seen[name] {
+			seen[name] = true
+			archives = append(archives, name)
+		}
+	}
+
+	return archives, nil
+}
+
+// archiveSeeds records the URLs the archives of -archive saw on the host of link (and its subdomains with -subs),
+// and returns the in-scope ones when -archive-crawl is present
+func (cr *crawl) archiveSeeds(link string) []string {
+	u, err := url.Parse(link)
+
+	if err != nil || u.Hostname() == "" {
+		return nil
+	}
+
+	seeds := make([]string, 0)
+
+	for _, archive := range cr.cfg.Archive {
+		var (
+			source string
+			urls   []string
+		)
+
+		switch archive {
+		case "wayback":
+			source, urls, err = cr.waybackURLs(u.Hostname())
+		case "otx":
+			source, urls, err = cr.otxURLs(u.Hostname())
+		case "commoncrawl":
+			source, urls, err = cr.commonCrawlURLs(u.Hostname())
+		}
+
+		if err != nil && err != errNoCaptures {
+			cr.logf("Skipping archive %s for %s: %v\n", archive, u.Hostname(), err)
+		}
+
+		for _, found := range urls {
+			found = lowerHost(found)
+
+			cr.addResult(Result{URL: found, Source: source, Type: "archive"})
+
+			if cr.cfg.ArchiveCrawl && IsWebURL(found) && cr.seedable(found) {
+				seeds = append(seeds, found)
+			}
+		}
+	}
+
+	return seeds
+}
+
+// matchType is the CDX match type of -subs, the host alone or its subdomains too
+func (cr *crawl) matchType() string {
+	if cr.cfg.SubsInScope {
+		return "domain"
+	}
+
+	return "host"
+}
+
+// waybackURLs queries the CDX API of the Wayback Machine, one line per distinct URL
+func (cr *crawl) waybackURLs(host string) (string, []string, error) {
+	query := url.Values{
+		"url":       {host},
+		"matchType": {cr.matchType()},
+		"fl":        {"original"},
+		"collapse":  {"urlkey"},
+		"limit":     {strconv.Itoa(maxArchiveURLs)},
+	}
+	link := waybackAPI + "?" + query.Encode()
+
+	body, err := cr.archiveGet(link)
+	if err != nil {
+		return link, nil, err
+	}
+
+	defer body.Close()
+
+	urls := make([]string, 0)
+	scanner := bufio.NewScanner(body)
+
+	for scanner.Scan() && len(urls) < maxArchiveURLs {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			urls = append(urls, line)
+		}
+	}
+
+	return link, urls, scanner.Err()
+}
+
+// otxURLs pages through the URL list AlienVault OTX keeps for the host, or for the domain with -subs
+func (cr *crawl) otxURLs(host string) (string, []string, error) {
+	kind := "hostname"
+	if cr.cfg.SubsInScope {
+		kind = "domain"
+	}
+
+	source := otxAPI + "/" + kind + "/" + url.PathEscape(host) + "/url_list"
+	urls := make([]string, 0)
+
+	for page := 1; len(urls) < maxArchiveURLs; page++ {
+		var list struct {
+			URLs []struct {
+				URL string `json:"url"`
+			} `json:"url_list"`
+			HasNext bool `json:"has_next"`
+		}
+
+		body, err := cr.archiveGet(source + "?limit=500&page=" + strconv.Itoa(page))
+		if err != nil {
+			return source, urls, err
+		}
+
+		err = json.NewDecoder(body).Decode(&list)
+		body.Close()
+
+		if err != nil {
+			return source, urls, err
+		}
+
+		for _, entry := range list.URLs {
+			urls = append(urls, entry.URL)
+		}
+
+		if !list.HasNext || len(list.URLs) == 0 {
+			break
+		}
+	}
+
+	return source, urls, nil
+}
+
+// commonCrawlURLs queries the latest Common Crawl index
+func (cr *crawl) commonCrawlURLs(host string) (string, []string, error) {
+	body, err := cr.archiveGet(commonCrawlIndex)
+	if err != nil {
+		return commonCrawlIndex, nil, err
+	}
+
+	var indexes []struct {
+		API string `json:"cdx-api"`
+	}
+
+	err = json.NewDecoder(body).Decode(&indexes)
+	body.Close()
+
+	if err != nil {
+		return commonCrawlIndex, nil, err
+	}
+
+	if len(indexes) == 0 {
+		return commonCrawlIndex, nil, fmt.Errorf("no index")
+	}
+
+	query := url.Values{
+		"url":       {host},
+		"matchType": {cr.matchType()},
+		"fl":        {"url"},
+		"output":    {"json"},
+		"limit":     {strconv.Itoa(maxArchiveURLs)},
+	}
+	link := indexes[0].API + "?" + query.Encode()
+
+	body, err = cr.archiveGet(link)
+	if err != nil {
+		return link, nil, err
+	}
+
+	defer body.Close()
+
+	seen := make(map[string]bool)
+	urls := make([]string, 0)
+	decoder := json.NewDecoder(body)
+
+	// an entry per capture, a URL captured many times comes back as many times
+	for len(urls) < maxArchiveURLs {
+		var capture struct {
+			URL string `json:"url"`
+		}
+
+		if err := decoder.Decode(&capture); err == io.EOF {
+			break
+		} else if err != nil {
+			return link, urls, err
+		}
+
+		if !seen[capture.URL] {
+			seen[capture.URL] = true
+			urls = append(urls, capture.URL)
+		}
+	}
+
+	return link, urls, nil
+}
+
+// archiveGet requests an archive API directly, without the headers, parameters and delays meant for the target.
+// The caller closes the body
+func (cr *crawl) archiveGet(link string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(cr.ctx, "GET", link, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", defaultUserAgent)
+
+	resp, err := cr.archives.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		resp.Body.Close()
+
+		if resp.StatusCode == 404 {
+			return nil, errNoCaptures
+		}
+
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	return resp.Body, nil
+}
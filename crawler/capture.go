@@ -0,0 +1,78 @@
+package crawler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gocolly/colly"
+)
+
+// unsafeFilenameRe matches the characters replaced in capture filenames
+var unsafeFilenameRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// SafeFilename replaces the runs of characters that don't belong in a filename with underscores
+func SafeFilename(name string) string {
+	return unsafeFilenameRe.ReplaceAllString(name, "_")
+}
+
+// capture writes the exchange of a URL matching -capture to the capture directory,
+// the request as colly sent it followed by the response (headers and decoded body)
+func (cr *crawl) capture(r *colly.Response) {
+	link := r.Request.URL.String()
+
+	if r.StatusCode == 0 || !cr.cfg.Capture.MatchString(link) {
+		return
+	}
+
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "%s %s HTTP/1.1\r\n", r.Request.Method, r.Request.URL.RequestURI())
+	fmt.Fprintf(&b, "Host: %s\r\n", r.Request.URL.Host)
+
+	if r.Request.Headers != nil {
+		r.Request.Headers.Write(&b)
+	}
+
+	fmt.Fprintf(&b, "\r\nHTTP/1.1 %d %s\r\n", r.StatusCode, http.StatusText(r.StatusCode))
+
+	if r.Headers != nil {
+		r.Headers.Write(&b)
+	}
+
+	b.WriteString("\r\n")
+	b.Write(r.Body)
+
+	path := filepath.Join(cr.cfg.CaptureDir, captureFilename(link))
+
+	if err := os.WriteFile(path, b.Bytes(), 0644); err != nil {
+		cr.logf("Could not write capture: %v\n", err)
+	}
+}
+
+// captureFilename turns a URL into a safe and unique filename,
+// e.g. https://example.com/a?b=c => example.com_a_b_c-<hash>.txt
+func captureFilename(link string) string {
+	name := link
+
+	// drop the scheme, every URL has one (the hash still tells http and https apart)
+	if _, rest, ok := strings.Cut(link, "://"); ok {
+		name = rest
+	}
+
+	name = strings.Trim(SafeFilename(name), "_")
+
+	if len(name) > 100 {
+		name = name[:100]
+	}
+
+	sum := sha256.Sum256([]byte(link))
+
+	return name + "-" + hex.EncodeToString(sum[:4]) + ".txt"
+}
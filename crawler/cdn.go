@@ -0,0 +1,52 @@
+package crawler
+
+import "strings"
+
+// DefaultCDNHosts are common CDN and third-party asset hosts skipped by -skip-cdn,
+// -cdn-list adds more. Each entry also covers its subdomains
+var DefaultCDNHosts = []string{
+	"ajax.aspnetcdn.com",
+	"ajax.googleapis.com",
+	"cdn.datatables.net",
+	"cdn.jsdelivr.net",
+	"cdnjs.cloudflare.com",
+	"code.jquery.com",
+	"connect.facebook.net",
+	"fonts.googleapis.com",
+	"fonts.gstatic.com",
+	"kit.fontawesome.com",
+	"maxcdn.bootstrapcdn.com",
+	"platform.twitter.com",
+	"stackpath.bootstrapcdn.com",
+	"unpkg.com",
+	"use.fontawesome.com",
+	"use.typekit.net",
+	"www.google-analytics.com",
+	"www.googletagmanager.com",
+}
+
+// isSkippedHost reports whether link points to one of the skipped hosts
+func (cr *crawl) isSkippedHost(link string) bool {
+	if len(cr.cfg.SkipHosts) == 0 {
+		return false
+	}
+
+	hostname, err := extractHostname(link)
+
+	return err == nil && matchesHost(hostname, cr.cfg.SkipHosts)
+}
+
+// matchesHost reports whether hostname is one of hosts or a subdomain of one of them
+func matchesHost(hostname string, hosts []string) bool {
+	hostname = strings.ToLower(hostname)
+
+	for _, host := range hosts {
+		host = strings.ToLower(host)
+
+		if hostname == host || strings.HasSuffix(hostname, "."+host) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,288 @@
+package crawler
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// httpOnlyPrefix starts the lines of HttpOnly cookies in the Netscape format, they aren't comments
+const httpOnlyPrefix = "#HttpOnly_"
+
+// CookieFile keeps the cookies of -cookie-file between runs, in the Netscape/cURL format.
+// Every crawl starts its own jar from it, and the cookies the servers set are saved back
+type CookieFile struct {
+	path string
+
+	mu      sync.Mutex
+	cookies map[cookieKey]storedCookie
+}
+
+// cookieKey identifies a cookie like browsers do, by domain, path and name
+type cookieKey struct {
+	domain string
+	path   string
+	name   string
+}
+
+// storedCookie is a line of the cookie file
+type storedCookie struct {
+	cookieKey
+	value string
+
+	// the cookie is sent to the subdomains of domain too
+	subdomains bool
+	secure     bool
+	httpOnly   bool
+
+	// zero for session cookies
+	expires time.Time
+}
+
+// LoadCookieFile reads a Netscape/cURL cookie file, a missing file has no cookies.
+// Expired cookies are dropped
+func LoadCookieFile(path string) (*CookieFile, error) {
+	cf := &CookieFile{path: path, cookies: make(map[cookieKey]storedCookie)}
+
+	f, err := os.Open(path)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return cf, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	now := time.Now()
+
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimRight(s.Text(), "\r")
+
+		httpOnly := strings.HasPrefix(line, httpOnlyPrefix)
+		line = strings.TrimPrefix(line, httpOnlyPrefix)
+
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// domain, subdomains, path, secure, expires, name and value, the value can be empty
+		fields := strings.Split(line, "\t")
+		if len(fields) == 6 {
+			fields = append(fields, "")
+		}
+
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("line %d: expected 7 tab-separated fields, got %d", n, len(fields))
+		}
+
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid expiry %q", n, fields[4])
+		}
+
+		c := storedCookie{
+			cookieKey:  cookieKey{domain: strings.ToLower(strings.TrimPrefix(fields[0], ".")), path: fields[2], name: fields[5]},
+			value:      fields[6],
+			subdomains: strings.EqualFold(fields[1], "TRUE"),
+			secure:     strings.EqualFold(fields[3], "TRUE"),
+			httpOnly:   httpOnly,
+		}
+
+		if expires != 0 {
+			c.expires = time.Unix(expires, 0)
+
+			if c.expires.Before(now) {
+				continue
+			}
+		}
+
+		cf.cookies[c.cookieKey] = c
+	}
+
+	return cf, s.Err()
+}
+
+// newCookieJar returns an empty jar, with the cookies of cf when it's set
+func newCookieJar(cf *CookieFile) *cookiejar.Jar {
+	// the public suffix list keeps servers from setting cookies for a whole TLD
+	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+
+	if cf == nil {
+		return jar
+	}
+
+	cf.mu.Lock()
+	defer cf.mu.Unlock()
+
+	for _, c := range cf.cookies {
+		scheme := "http"
+		if c.secure {
+			scheme = "https"
+		}
+
+		cookie := &http.Cookie{Name: c.name, Value: c.value, Path: c.path, Secure: c.secure, HttpOnly: c.httpOnly, Expires: c.expires}
+
+		// host-only cookies have no Domain attribute
+		if c.subdomains {
+			cookie.Domain = c.domain
+		}
+
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: c.domain, Path: c.path}, []*http.Cookie{cookie})
+	}
+
+	return jar
+}
+
+// update remembers the cookies a response set, like the jar accepts them
+func (cf *CookieFile) update(u *url.URL, cookies []*http.Cookie) {
+	host := strings.ToLower(u.Hostname())
+	now := time.Now()
+
+	cf.mu.Lock()
+	defer cf.mu.Unlock()
+
+	for _, cookie := range cookies {
+		c := storedCookie{
+			cookieKey: cookieKey{domain: host, path: cookie.Path, name: cookie.Name},
+			value:     cookie.Value,
+			secure:    cookie.Secure,
+			httpOnly:  cookie.HttpOnly,
+		}
+
+		// a Domain attribute shares the cookie with the subdomains, it must cover the host
+		if domain := strings.ToLower(strings.TrimPrefix(cookie.Domain, ".")); domain != "" && domain != host {
+			if suffix, _ := publicsuffix.PublicSuffix(domain); suffix == domain || !strings.HasSuffix(host, "."+domain) {
+				continue
+			}
+
+			c.domain = domain
+		}
+		c.subdomains = cookie.Domain != ""
+
+		if !strings.HasPrefix(c.path, "/") {
+			c.path = defaultCookiePath(u.Path)
+		}
+
+		switch {
+		case cookie.MaxAge > 0:
+			c.expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		case cookie.MaxAge == 0 && !cookie.Expires.IsZero():
+			c.expires = cookie.Expires
+		}
+
+		// servers delete cookies by expiring them
+		if cookie.MaxAge < 0 || (!c.expires.IsZero() && c.expires.Before(now)) {
+			delete(cf.cookies, c.cookieKey)
+			continue
+		}
+
+		cf.cookies[c.cookieKey] = c
+	}
+}
+
+// defaultCookiePath is the path of a cookie set without one, the directory of the request path (RFC 6265)
+func defaultCookiePath(requestPath string) string {
+	if !strings.HasPrefix(requestPath, "/") || strings.Count(requestPath, "/") == 1 {
+		return "/"
+	}
+
+	return path.Dir(requestPath)
+}
+
+// Save writes the cookies back to the file, session cookies included so the session goes on
+func (cf *CookieFile) Save() error {
+	cf.mu.Lock()
+	defer cf.mu.Unlock()
+
+	cookies := make([]storedCookie, 0, len(cf.cookies))
+	for _, c := range cf.cookies {
+		cookies = append(cookies, c)
+	}
+
+	sort.Slice(cookies, func(i, j int) bool {
+		a, b := cookies[i].cookieKey, cookies[j].cookieKey
+
+		if a.domain != b.domain {
+			return a.domain < b.domain
+		}
+		if a.path != b.path {
+			return a.path < b.path
+		}
+		return a.name < b.name
+	})
+
+	f, err := os.Create(cf.path)
+
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, "# Netscape HTTP Cookie File")
+
+	for _, c := range cookies {
+		domain := c.domain
+		if c.subdomains {
+			domain = "." + domain
+		}
+		if c.httpOnly {
+			domain = httpOnlyPrefix + domain
+		}
+
+		var expires int64
+		if !c.expires.IsZero() {
+			expires = c.expires.Unix()
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", domain, netscapeBool(c.subdomains), c.path, netscapeBool(c.secure), expires, c.name, c.value)
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func netscapeBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+
+	return "FALSE"
+}
+
+// cookieTransport hands the cookies set by every response, redirects included, to a cookie file
+type cookieTransport struct {
+	next  http.RoundTripper
+	store *CookieFile
+}
+
+func (t *cookieTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+
+	if err == nil {
+		if cookies := resp.Cookies(); len(cookies) > 0 {
+			t.store.update(req.URL, cookies)
+		}
+	}
+
+	return resp, err
+}
@@ -0,0 +1,1808 @@
+/*
+	Author	=> Abdallah Mohamed Elsharif
+	Email	=> elsharifabdallah53@gmail.com
+	Date	=> 3-1-2022
+*/
+
+// Package crawler crawls web sites and collects the URLs they link to.
+// Set up a Config and call StartCrawler, or reuse it for many targets with a Crawler
+package crawler
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gocolly/colly"
+)
+
+// Config holds the settings of a single crawl
+type Config struct {
+	// Number of threads to utilise
+	Threads int
+
+	// Depth to crawl
+	Depth int
+
+	// Include subdomains for crawling
+	SubsInScope bool
+
+	// Disable TLS verification
+	Insecure bool
+
+	// CAs trusted for TLS verification, the system ones when nil
+	RootCAs *x509.CertPool
+
+	// Accept-Encoding header of every request, Go's "gzip" when empty
+	AcceptEncoding string
+
+	// Keep compressed bodies compressed instead of decompressing them
+	RawBody bool
+
+	// Custom headers separated by two semi-colons
+	RawHeaders string
+
+	// User-Agent of the requests, a preset (desktop, mobile, googlebot) or the header itself. Firefox 78 when empty.
+	// With RandomAgent every request picks one of a list of current browsers instead
+	UserAgent   string
+	RandomAgent bool
+
+	// Cookies every crawl starts with on the target, "name=value; name2=value2" like a Cookie header
+	RawCookies string
+
+	// Request to post LoginData to before crawling, the session cookies it sets are sent afterwards
+	LoginURL  string
+	LoginData string
+
+	// "user:password" answering HTTP Digest challenges
+	DigestAuth string
+
+	// When set, decides which discovered links are followed instead of the host/-subs scope
+	ScopeExpr *ScopeExpr
+
+	// When set, only the links matching IncludeRegex and not matching ExcludeRegex are followed.
+	// Links whose path has one of the IgnoreExt extensions (lowercase, without the dot) aren't either.
+	// The links are recorded anyway
+	IncludeRegex *regexp.Regexp
+	ExcludeRegex *regexp.Regexp
+	IgnoreExt    map[string]bool
+
+	// Links matching one of DenyRegex are never requested, not even by a redirect or -verify. They're recorded anyway.
+	// SafeMode denies the logout, sign out, delete and similar links too, an authenticated crawl would end its
+	// session or destroy data requesting them
+	DenyRegex []*regexp.Regexp
+	SafeMode  bool
+
+	// When set, only links on these ports are followed (default ports count for URLs without one)
+	Ports []int
+
+	// Don't record the URLs outside the crawl scope (they are never followed anyway)
+	SkipExternal bool
+
+	// Record a single result per normalized URL (see normalizeURL), the first spelling found is kept.
+	// With UniqueSlash, a trailing slash doesn't make a URL different either
+	Unique      bool
+	UniqueSlash bool
+
+	// Fetch the input URL verbatim and only extract its links, nothing is followed
+	// and neither a scheme nor a scope is inferred
+	Exact bool
+
+	// Crawl order, "bfs" or "dfs". Empty visits links concurrently as they are found
+	Order string
+
+	// Hosts (and their subdomains) that are neither recorded nor crawled
+	SkipHosts []string
+
+	// Follow JavaScript modules and record the modules they import
+	Modules bool
+
+	// Record the URLs of JSON-LD blocks and microdata properties
+	StructuredData bool
+
+	// Fingerprint each host's response to a missing page and suppress crawled pages matching it
+	DetectSoft404 bool
+
+	// Once a host other than the crawled one had HostThreshold pages crawled,
+	// its pages deeper than HostDepth are skipped. 0 disables the cap
+	HostThreshold int
+	HostDepth     int
+
+	// Upgrade http links to https on hosts that sent a Strict-Transport-Security header
+	HSTS bool
+
+	// Query parameters added to every request, recorded URLs don't carry them
+	AppendParams url.Values
+
+	// Send the page a link was found on as the Referer of its request
+	AutoReferer bool
+
+	// Flag the links opening a new window without rel="noopener"
+	Tabnabbing bool
+
+	// Load the HTML pages in headless Chrome and extract the links of the rendered DOM, plus the URLs
+	// the page requested with XMLHttpRequest or fetch
+	Render bool
+
+	// Record the status, content type, length and redirects of the visited pages
+	ShowStatus bool
+
+	// Record the method and fields of forms (those without action too) and the parameter names of every URL
+	Forms bool
+
+	// When set, only the URLs matching Match and not matching FilterOut are recorded, the crawl still follows the others
+	Match     *regexp.Regexp
+	FilterOut *regexp.Regexp
+
+	// Only the links of pages whose body matches ExpandIf are followed, the others are still recorded
+	ExpandIf *regexp.Regexp
+
+	// Record the paths of the Allow and Disallow rules of robots.txt and crawl the in-scope ones
+	// (the disallowed ones only with IgnoreRobots)
+	Robots bool
+
+	// Record the URLs of the sitemaps (/sitemap.xml and the ones robots.txt lists) and crawl the in-scope ones
+	Sitemap bool
+
+	// Record the URLs these web archives saw on the target ("wayback", "otx", "commoncrawl"),
+	// with ArchiveCrawl the in-scope ones are crawled too
+	Archive      []string
+	ArchiveCrawl bool
+
+	// Follow web app manifests and service workers (linked, registered or at their well-known paths)
+	// and record and follow the URLs they list
+	Manifests bool
+
+	// Also record the URLs of <link>, <img> and <source> elements (stylesheets, icons, images, media),
+	// of srcset attributes and of HTML comments
+	All bool
+
+	// Fetch the in-scope scripts and record the URLs quoted in them (and in inline scripts)
+	JS bool
+
+	// Follow the in-scope sources of iframes like links, they often embed separate apps
+	FollowIframes bool
+
+	// Record the robots directives of visited pages (<meta name="robots"> and X-Robots-Tag)
+	RobotsMeta bool
+
+	// Record the SHA-256 of the body of every fetched page, for change detection and duplicate grouping
+	HashBodies bool
+
+	// Request every recorded http(s) URL after crawling and record its status,
+	// with LiveOnly the URLs that don't answer with a status below 400 are dropped
+	Verify   bool
+	LiveOnly bool
+
+	// How long a single request (and reading its body) may take, 10 seconds when 0.
+	// Retries and -timing-profile delays don't count
+	Timeout time.Duration
+
+	// Every request goes through this proxy (http://, https:// or socks5://) instead of
+	// the one of HTTPS_PROXY/HTTP_PROXY. Comma-separated proxies take turns, one request each.
+	// An invalid URL makes StartCrawler return no results
+	Proxy string
+
+	// When set, every request waits for a delay drawn from the profile
+	TimingProfile *TimingProfile
+
+	// The exchanges of the URLs matching Capture are written to CaptureDir, one file per URL
+	Capture    *regexp.Regexp
+	CaptureDir string
+
+	// Fraction of the discovered links that are followed (every link is recorded), 0 or 1 follow all.
+	// Seed feeds every random choice of the crawl (the sample, -timing-profile delays, soft-404 probes)
+	// so it's reproducible with a single thread or -order, 0 seeds with the time
+	Sample float64
+	Seed   int64
+
+	// Wait between the requests to a domain, plus a random extra up to RandomDelay
+	Delay       time.Duration
+	RandomDelay time.Duration
+
+	// Maximum number of concurrent DNS lookups, 0 doesn't limit them
+	MaxDNS int
+
+	// Address (IP or hostname, with an optional port) the connections to the target's host go to instead,
+	// for virtual hosts and origins behind a CDN. The URLs, the Host header and the TLS server name stay
+	// the target's, so do the scope and the results. Connections through a proxy aren't affected
+	HostOverride string
+
+	// Stop crawling and report the error on the first failed request
+	FailFast bool
+
+	// Stop crawling once this many pages were requested, keeping the results found so far. 0 doesn't limit them
+	MaxRequests int
+
+	// Redirects followed per request, 10 when 0. FollowRedirects "scope" doesn't follow the redirects leaving
+	// the crawl scope, "none" doesn't follow any. The targets of the redirects not followed are recorded
+	// with type redirect. Empty or "all" follows what colly allows
+	MaxRedirects    int
+	FollowRedirects string
+
+	// Don't fetch robots.txt. Otherwise in-scope URLs it disallows are neither requested nor recorded
+	IgnoreRobots bool
+
+	// When set, it's called once per HTTPS host with the details of its certificate
+	OnTLSInfo func(info TLSInfo)
+
+	// When set, the crawls sharing it record a URL once between them: the crawl finding it first
+	// records it, the others leave it out. Each crawl records every URL it finds otherwise
+	Shared *URLSet
+
+	// When set, it's called with every result as soon as it's recorded, from the crawling goroutines.
+	// The checks made once the crawl is done (soft-404 filtering, -verify) only apply to the returned results
+	OnResult func(result Result)
+
+	// Crawl the certificate SANs of the target's organization (same registrable domain) as new seeds,
+	// at most MaxHosts of them
+	ExpandSANs bool
+	MaxHosts   int
+
+	// Scheme of the URLs given without one, "http" (the default), "https",
+	// or "auto" to try https first and fall back to http
+	DefaultScheme string
+
+	// URLs with more query parameters than MaxParams are neither recorded nor crawled. 0 disables the check
+	MaxParams int
+
+	// When set, every crawl starts with these cookies, and the cookies set during the crawl are added to them
+	Cookies *CookieFile
+
+	// When set, pages are requested with the validators a previous run stored (If-None-Match,
+	// If-Modified-Since), pages answering 304 are recorded as unchanged and their links aren't followed.
+	// ForceRefresh doesn't send the validators and only updates the store
+	Validators   *ValidatorStore
+	ForceRefresh bool
+
+	// Number of times failed requests and the ones answering one of RetryCodes
+	// (429 and 5xx gateway errors when nil) are retried, the first retry waits RetryBackoff
+	// and every other one twice as long as the previous one
+	Retries      int
+	RetryCodes   []int
+	RetryBackoff time.Duration
+
+	// The in-scope GET requests of a recorded browser session are crawled as extra seeds,
+	// with HARCookies the cookies the browser sent go along
+	HAR        *HARSession
+	HARCookies bool
+
+	// When set, the crawl reports visited pages, new URLs, errors and retries as they happen
+	Events *EventLog
+
+	// When set, the requests, responses and errors of the crawl are counted there as they happen
+	Metrics *Metrics
+
+	// When set, every request of the crawl and its response are written to the archive
+	HAROut *HARLog
+
+	// When set, the crawl checkpoints its progress there and picks up from the checkpoint a previous run left
+	State *CrawlState
+
+	// When set, only the hosts whose addresses the filter allows are crawled, links to the others are still recorded
+	Infra *InfraFilter
+
+	// Links extracted from a single page beyond MaxLinks are ignored. 0 disables the cap
+	MaxLinks int
+
+	// Ceiling of the goroutines visiting links (and verifying them), 0 doesn't cap them.
+	// When it's set, links wait in a queue instead of getting a goroutine each as they are found
+	MaxGoroutines int
+
+	// The MaxGoroutines ceiling shared by the crawls using this Config, see NewGoroutineLimit.
+	// Each crawl has a ceiling of its own when it's nil
+	Goroutines *GoroutineLimit
+
+	// Print warnings about ignored links and skipped work to Log
+	Verbose bool
+
+	// Where the warnings and notices of the crawl are written, they're discarded when nil.
+	// It must be safe for concurrent use
+	Log io.Writer
+
+	// called before every other OnRequest callback, used by EstimateRequests
+	beforeRequest func(r *colly.Request)
+}
+
+// default timeout of a request
+const defaultTimeout = 10 * time.Second
+
+// default user agent header
+const defaultUserAgent = "Mozilla/5.0 (X11; Linux x86_64; rv:78.0) Gecko/20100101 Firefox/78.0"
+
+// crawl is the state of a single StartCrawler call, shared by the colly callbacks
+type crawl struct {
+	ctx      context.Context
+	cfg      *Config
+	hostname string
+	results  *resultSet
+
+	// the target as given, -state keeps the checkpoints under it
+	target string
+
+	// with -order or -max-goroutines, links wait in a frontier instead of being visited right away
+	queue *frontier
+
+	// URLs followed as JavaScript modules
+	modules sync.Map
+
+	// URLs followed as web app manifests (false) or service workers (true), for -manifests
+	manifests sync.Map
+
+	// plain HTTP client sharing the collector's transport, for requests made outside colly
+	client  *http.Client
+	headers map[string]string
+
+	// client of the web archives, the target's headers, parameters and delays don't apply to them
+	archives *http.Client
+
+	// soft-404 fingerprints per host and the pages matching them
+	soft404s soft404Detector
+
+	// requests made per host, for -host-threshold
+	hostPages hostCounter
+
+	// hosts known to use HSTS, for -hsts
+	hsts hstsHosts
+
+	// followed URL => page it was first found on, for -auto-referer
+	referers sync.Map
+
+	// request ID => links extracted from its page so far, for -max-links
+	pageLinks sync.Map
+
+	// decides which links -sample follows
+	rng *lockedRand
+
+	// set once a request failed with -fail-fast
+	failed int32
+
+	// pages requested so far, for -max-requests
+	requests int64
+
+	// SAN hostnames seeded so far, and the ones waiting to be crawled, for -expand-sans
+	sanMu    sync.Mutex
+	sanHosts map[string]bool
+	sanSeeds []string
+
+	// pages whose body doesn't match -expand-if
+	unexpanded sync.Map
+
+	// origin => its robots.txt, and the URLs reported as disallowed
+	robots        sync.Map
+	robotsBlocked sync.Map
+
+	// URLs reported as denied with -verbose
+	denyReported sync.Map
+
+	// with -show-status, follows the redirect chains
+	tracker *statusTracker
+
+	// pages requested and done, for -state
+	progress *crawlProgress
+
+	// highest goroutine count seen, reported with -verbose
+	goroutines goroutinePeak
+
+	// the -max-goroutines ceiling, shared with the other crawls of the run when Config has one
+	slots *GoroutineLimit
+
+	// host => whether -asn-db allows it
+	infraHosts sync.Map
+}
+
+// logf writes a warning or notice of the crawl to its Log
+func (cr *crawl) logf(format string, args ...interface{}) {
+	if cr.cfg.Log != nil {
+		fmt.Fprintf(cr.cfg.Log, format, args...)
+	}
+}
+
+// get requests link with the crawl's client, user agent and custom headers
+func (cr *crawl) get(link string) (*http.Response, error) {
+	return cr.do("GET", link)
+}
+
+// do makes a request like get with any method
+func (cr *crawl) do(method string, link string) (*http.Response, error) {
+	req, err := http.NewRequest(method, link, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", cr.userAgent())
+
+	for header, value := range cr.headers {
+		req.Header.Set(header, value)
+	}
+
+	return cr.client.Do(req)
+}
+
+// addResult records a valid result unless it's filtered out
+func (cr *crawl) addResult(result Result) {
+	if result.URL == "" || cr.isSkippedHost(result.URL) {
+		return
+	}
+
+	// -match and -filter-out only decide what is written, links are followed either way
+	if (cr.cfg.Match != nil && !cr.cfg.Match.MatchString(result.URL)) || (cr.cfg.FilterOut != nil && cr.cfg.FilterOut.MatchString(result.URL)) {
+		return
+	}
+
+	// without -record-external, only the URLs that could be crawled are recorded
+	if cr.cfg.SkipExternal && !cr.inScope(result.URL, 0) {
+		return
+	}
+
+	if cr.tooManyParams(result.URL) {
+		if cr.cfg.Verbose {
+			cr.logf("Skipping URL with more than %d query parameters: %s\n", cr.cfg.MaxParams, result.URL)
+		}
+		return
+	}
+
+	// soft-404 pages are error pages, nothing on them is recorded (like colly does for real ones)
+	if cr.soft404s.isSoft404(result.Source) {
+		return
+	}
+
+	// the URLs the crawl can't request aren't recorded either, links to other sites are.
+	// The paths robots.txt itself lists are, -robots is there to find them
+	if !cr.cfg.IgnoreRobots && result.Type != "robots" && cr.inScope(result.URL, 0) && !cr.robotsAllowed(result.URL) {
+		cr.reportRobots(result.URL)
+		return
+	}
+
+	if cr.cfg.Forms {
+		result.Params = paramNames(result.URL, result.Params)
+	} else {
+		result.Method, result.Inputs, result.Params = "", nil, nil
+	}
+
+	if recorded, ok := cr.results.add(result); ok {
+		if cr.cfg.Metrics != nil {
+			atomic.AddInt64(&cr.cfg.Metrics.urls, 1)
+		}
+
+		cr.emit(Event{Event: "found", URL: result.URL, Source: result.Source, Type: result.Type})
+
+		if cr.cfg.OnResult != nil {
+			cr.cfg.OnResult(recorded)
+		}
+	}
+}
+
+// tooManyParams reports whether the query of link has more parameters than -max-params allows
+func (cr *crawl) tooManyParams(link string) bool {
+	if cr.cfg.MaxParams <= 0 {
+		return false
+	}
+
+	u, err := url.Parse(link)
+
+	if err != nil {
+		return false
+	}
+
+	count := 0
+	query, _ := url.ParseQuery(u.RawQuery)
+
+	for _, values := range query {
+		count += len(values)
+	}
+
+	return count > cr.cfg.MaxParams
+}
+
+// append valid unique result to results
+func (cr *crawl) appendResult(link string, kind string, r *colly.Request) {
+	cr.addResult(newResult(link, kind, r))
+}
+
+// follow visits a link found on the page requested by r.
+// Recording and following are separate decisions, a link can be recorded without being followed
+func (cr *crawl) follow(r *colly.Request, link string) {
+	// -exact only extracts from the input URLs
+	if cr.cfg.Exact {
+		return
+	}
+
+	absolute := lowerHost(r.AbsoluteURL(link))
+
+	if absolute == "" || cr.isSkippedHost(absolute) || cr.tooManyParams(absolute) {
+		return
+	}
+
+	// visit the link with the lowercased host so mixed-case hostnames stay in scope
+	link = absolute
+
+	// nothing is queued once the crawl is cancelled
+	if cr.cancelled() {
+		return
+	}
+
+	// and nothing on them is followed
+	if cr.soft404s.isSoft404(r.URL.String()) {
+		return
+	}
+
+	// pages of other apps are recorded but not expanded with -expand-if
+	if _, ok := cr.unexpanded.Load(r.URL.String()); ok {
+		return
+	}
+
+	// links to other services of the host are recorded but not followed
+	if len(cr.cfg.Ports) > 0 && !portAllowed(absolute, cr.cfg.Ports) {
+		return
+	}
+
+	// external links (and the ones the scope expression rejects) are recorded but never visited
+	if !cr.inScope(absolute, r.Depth+1) {
+		return
+	}
+
+	// and so are the links -include-regex, -exclude-regex or -ignore-ext leave out
+	if !cr.visitable(absolute) {
+		return
+	}
+
+	// with -sample, only a random fraction of the links is followed
+	if !cr.sampled() {
+		return
+	}
+
+	// mirror browsers and skip the redirect to https
+	if cr.cfg.HSTS {
+		link = cr.hsts.upgrade(absolute)
+	}
+
+	// the first page linking to a URL becomes its referer
+	if cr.cfg.AutoReferer {
+		cr.referers.LoadOrStore(link, r.URL.String())
+	}
+
+	cr.visit(nil, r, link, 0)
+}
+
+// visit hands link to colly, as found on parent or as a starting URL of c when parent is nil,
+// or queues it in the frontier with -order and -max-goroutines. A depth above 0 replaces the
+// depth of its request, for -resume
+func (cr *crawl) visit(c *colly.Collector, parent *colly.Request, link string, depth int) {
+	item := frontierItem{parent: parent, link: link, depth: depth}
+
+	// with -state the link is pending until its page is done, as colly remembers it once visited
+	if cr.progress != nil {
+		raw := link
+		if parent != nil {
+			raw = parent.AbsoluteURL(link)
+		}
+
+		item.key = cr.progress.schedule(raw, item.requestDepth())
+	}
+
+	if cr.queue != nil {
+		cr.queue.push(item)
+	} else {
+		cr.handOff(c, item)
+	}
+}
+
+// handOff makes colly request the link of item
+func (cr *crawl) handOff(c *colly.Collector, item frontierItem) {
+	var err error
+
+	if item.parent != nil {
+		err = item.parent.Visit(item.link)
+	} else {
+		err = c.Visit(item.link)
+	}
+
+	// colly refused it, e.g. it was already visited
+	if err != nil && item.key != "" {
+		cr.finishLevel(cr.progress.unschedule(item.key))
+	}
+}
+
+// visitQueued visits the links of the frontier
+func (cr *crawl) visitQueued(c *colly.Collector) func(item frontierItem) {
+	return func(item frontierItem) {
+		cr.limited(func() {
+			cr.handOff(c, item)
+		})
+	}
+}
+
+// Crawler crawls targets with the same configuration, it's safe for concurrent use
+type Crawler struct {
+	cfg Config
+}
+
+// New returns a crawler using cfg, it keeps a copy so later changes to cfg don't affect it
+func New(cfg Config) *Crawler {
+	return &Crawler{cfg: cfg}
+}
+
+// Crawl crawls url until ctx is done, see StartCrawlerContext
+func (c *Crawler) Crawl(ctx context.Context, url string) []Result {
+	return StartCrawlerContext(ctx, url, &c.cfg)
+}
+
+// Stream crawls url until ctx is done and hands every result to found as soon as it's recorded,
+// see StartCrawlerStream
+func (c *Crawler) Stream(ctx context.Context, url string, found func(result Result)) {
+	streamed := c.cfg
+	streamed.OnResult = found
+
+	StartCrawlerContext(ctx, url, &streamed)
+}
+
+// StartCrawler crawls url as cfg says and returns the URLs found
+func StartCrawler(url string, cfg *Config) []Result {
+	return StartCrawlerContext(context.Background(), url, cfg)
+}
+
+// StartCrawlerStream crawls like StartCrawler and hands every result to found as soon as it's recorded,
+// found must be safe for concurrent use
+func StartCrawlerStream(url string, cfg *Config, found func(result Result)) {
+	streamed := *cfg
+	streamed.OnResult = found
+
+	StartCrawler(url, &streamed)
+}
+
+// StartCrawlerContext crawls like StartCrawler until ctx is done. Then no request is started,
+// requests in flight are cut short, and the results found so far are returned
+func StartCrawlerContext(ctx context.Context, url string, cfg *Config) []Result {
+	return startCrawler(ctx, url, cfg, nil)
+}
+
+// startCrawler crawls url, the requests are counted in stats when it's set
+func startCrawler(ctx context.Context, url string, cfg *Config, stats *Stats) []Result {
+
+	// Convert the headers input to a usable map (or die trying)
+	headers, _ := parseHeaders(cfg.RawHeaders)
+
+	// set the encodings if -accept-encoding is present, -h can still override it
+	if cfg.AcceptEncoding != "" {
+		headers = withDefault(headers, "Accept-Encoding", cfg.AcceptEncoding)
+	}
+
+	// the state is kept under the target as given
+	target := url
+
+	// a crawl -resume finds done returns its results again
+	var saved *targetState
+	if cfg.State != nil {
+		saved = cfg.State.get(target)
+
+		if saved != nil && saved.Done {
+			if cfg.OnResult != nil {
+				for _, result := range saved.Results {
+					cfg.OnResult(result)
+				}
+			}
+
+			return saved.Results
+		}
+	}
+
+	cr := &crawl{ctx: ctx, cfg: cfg, target: target, results: newResults(cfg), rng: newLockedRand(cfg.Seed), sanHosts: make(map[string]bool)}
+
+	// if a url does not start with scheme (It fix hakrawler bug), it gets -default-scheme: http by default, auto tries https first.
+	// -exact takes the URL as it is
+	schemeless := !strings.Contains(url, "://") && !cfg.Exact
+	if schemeless {
+		url = defaultScheme(cfg.DefaultScheme) + "://" + url
+	}
+
+	// Get hostname from url
+	if !cfg.Exact {
+		url = lowerHost(url)
+	}
+	hostname, err := extractHostname(url)
+
+	if err != nil {
+		// return empty slice
+		return cr.results.list()
+	}
+
+	cr.hostname = hostname
+	cr.headers = headers
+
+	// count the crawl in -stats progress and -metrics
+	if cfg.Metrics != nil {
+		atomic.AddInt64(&cfg.Metrics.running, 1)
+
+		defer func() {
+			atomic.AddInt64(&cfg.Metrics.running, -1)
+			atomic.AddInt64(&cfg.Metrics.done, 1)
+		}()
+	}
+
+	// the callbacks run in the order they're registered: the request checks first, then the
+	// extraction, and the rendering last
+	c := cr.newCollector(stats)
+	cr.extractLinks(c)
+	cr.recordResponses(c)
+	cr.shapeRequests(c)
+
+	if cfg.Render {
+		defer cr.renderPages(c)()
+	}
+
+	jar, err := cr.setTransport(c)
+	if err != nil {
+		cr.logf("Invalid proxy: %v\n", err)
+		return cr.results.list()
+	}
+
+	// fall back to http when https doesn't answer
+	if schemeless && cfg.DefaultScheme == "auto" {
+		url = cr.fallbackToHTTP(url)
+	}
+
+	cr.startSession(jar, url)
+
+	// pick up where the previous run stopped if -resume is present
+	if saved != nil {
+		cr.resume(c, jar, saved)
+	}
+
+	seeds := cr.seeds(c, url)
+
+	// checkpoint regularly if -state is present
+	stopCheckpoints := func() {}
+	if cfg.State != nil {
+		stopCheckpoints = cr.checkpoints(target)
+	}
+
+	cr.run(c, seeds)
+
+	stopCheckpoints()
+
+	return cr.finish(target)
+}
+
+// newResults returns the container the results of a crawl are stored in
+func newResults(cfg *Config) *resultSet {
+	results := newResultSet()
+
+	// collapse the spellings of the same URL if -unique is present
+	if cfg.Unique || cfg.UniqueSlash {
+		results.key = normalizeURL
+
+		if cfg.UniqueSlash {
+			results.key = func(link string) string {
+				return trimTrailingSlash(normalizeURL(link))
+			}
+		}
+	}
+
+	// leave out what the other crawls sharing the set recorded
+	results.shared = cfg.Shared
+
+	return results
+}
+
+// newCollector returns the collector of the crawl in its scope, with the callbacks deciding
+// whether a request is made
+func (cr *crawl) newCollector(stats *Stats) *colly.Collector {
+	cfg := cr.cfg
+
+	// Instantiate default collector
+	c := colly.NewCollector(
+
+		// user agent header, -ua-rand replaces it on every request
+		colly.UserAgent(resolveUserAgent(cfg.UserAgent)),
+
+		// limit crawling to the domain of the specified URL
+		colly.AllowedDomains(cr.hostname),
+
+		// set MaxDepth to the specified depth
+		colly.MaxDepth(cfg.Depth),
+
+		// specify Async for threading, ordered and capped crawls bring their own workers
+		colly.Async(cfg.Order == "" && cfg.MaxGoroutines == 0),
+	)
+
+	if cfg.Order != "" || cfg.MaxGoroutines > 0 {
+		cr.queue = newFrontier(cfg.Order)
+
+		if cfg.MaxGoroutines > 0 {
+			cr.slots = cfg.Goroutines
+			if cr.slots == nil {
+				cr.slots = NewGoroutineLimit(cfg.MaxGoroutines)
+			}
+		}
+
+		if cfg.Metrics != nil {
+			cr.queue.queued = &cfg.Metrics.queued
+		}
+	}
+
+	if cfg.Exact {
+		// with -exact nothing is followed, there's no scope to infer
+		c.AllowedDomains = nil
+	} else if cfg.ScopeExpr != nil {
+		// if -scope-expr is present, the expression alone decides what is followed
+		c.AllowedDomains = nil
+	} else if cfg.SubsInScope {
+		// if -subs is present, use regex to filter out subdomains in scope.
+		c.AllowedDomains = nil
+		c.URLFilters = []*regexp.Regexp{subsFilter(cr.hostname)}
+	}
+
+	if cfg.beforeRequest != nil {
+		c.OnRequest(cfg.beforeRequest)
+	}
+
+	if stats != nil {
+		countRequests(c, stats)
+	}
+
+	if cfg.Metrics != nil {
+		cfg.Metrics.watch(c)
+	}
+
+	// follow the progress for the checkpoints if -state is present
+	if cfg.State != nil {
+		cr.progress = newCrawlProgress()
+
+		c.OnRequest(cr.progress.request)
+
+		c.OnScraped(func(r *colly.Response) {
+			cr.finishLevel(cr.progress.finish(r.Request))
+		})
+
+		c.OnError(func(r *colly.Response, err error) {
+			cr.finishLevel(cr.progress.finish(r.Request))
+		})
+	}
+
+	// decide whether the links of a page are followed before any callback gets them, if -expand-if is present
+	if cfg.ExpandIf != nil {
+		c.OnResponse(func(r *colly.Response) {
+			if !cfg.ExpandIf.Match(r.Body) {
+				cr.unexpanded.Store(r.Request.URL.String(), true)
+			}
+		})
+	}
+
+	// stream the progress if -events-out is present
+	if cfg.Events != nil {
+		c.OnResponse(func(r *colly.Response) {
+			cr.emit(Event{Event: "visit", URL: r.Request.URL.String(), Status: r.StatusCode})
+		})
+
+		c.OnError(func(r *colly.Response, err error) {
+			cr.emit(Event{Event: "error", URL: r.Request.URL.String(), Status: r.StatusCode, Error: err.Error()})
+		})
+	}
+
+	if cfg.Verbose {
+		c.OnRequest(func(r *colly.Request) {
+			cr.goroutines.sample()
+		})
+	}
+
+	// stay on the allowed infrastructure if -asn-db is present, the starting URL included
+	if cfg.Infra != nil {
+		c.OnRequest(func(r *colly.Request) {
+			if !cr.onAllowedInfra(r.URL.Hostname()) {
+				cr.skip(r)
+			}
+		})
+	}
+
+	// obey robots.txt unless -ignore-robots is present, the starting URL included
+	if !cfg.IgnoreRobots {
+		c.OnRequest(func(r *colly.Request) {
+			if !cr.robotsAllowed(r.URL.String()) {
+				cr.reportRobots(r.URL.String())
+				cr.skip(r)
+			}
+		})
+	}
+
+	// never request the links of -deny and -safe
+	if len(cfg.DenyRegex) > 0 || cfg.SafeMode {
+		c.OnRequest(func(r *colly.Request) {
+			cr.deny(r)
+		})
+	}
+
+	// wind down once the context is done, -max-time or -batch-maxtime are up
+	c.OnRequest(func(r *colly.Request) {
+		if cr.cancelled() {
+			r.Abort()
+		}
+	})
+
+	// stop at the first error if -fail-fast is present
+	if cfg.FailFast {
+		c.OnRequest(func(r *colly.Request) {
+			cr.stopIfFailed(r)
+		})
+
+		c.OnError(func(r *colly.Response, err error) {
+			cr.fail(r, err)
+		})
+	}
+
+	// stop once the -max-requests budget is spent
+	if cfg.MaxRequests > 0 {
+		c.OnRequest(func(r *colly.Request) {
+			cr.spend(r)
+		})
+	}
+
+	// Set parallelism, -delay and -random-delay are waited for in the transport of the collector
+	c.Limit(&colly.LimitRule{DomainGlob: "*", Parallelism: cfg.Threads})
+	return c
+}
+
+// extractLinks records the links of the pages and follows the ones that are crawled
+func (cr *crawl) extractLinks(c *colly.Collector) {
+	cfg := cr.cfg
+
+	// append every href found (image map areas included), and visit it
+	c.OnHTML("a[href], area[href]", func(e *colly.HTMLElement) {
+		if !cr.allowLink(e.Request) {
+			return
+		}
+
+		kind := "href"
+		if e.Name == "area" {
+			kind = "area"
+		}
+
+		link := e.Attr("href")
+		result := newResult(link, kind, e.Request)
+
+		// anchors (and areas) carrying a download attribute point to downloadable files
+		if filename, ok := e.DOM.Attr("download"); ok {
+			result.Download = true
+			result.Filename = filename
+		}
+
+		result.Target = e.Attr("target")
+		result.Rel = e.Attr("rel")
+
+		// new windows opened without noopener can navigate their opener (reverse tabnabbing)
+		if cfg.Tabnabbing && isTabnabbable(result.Target, result.Rel) {
+			result.Tabnabbing = true
+			cr.logf("Possible reverse tabnabbing: %s on %s\n", result.URL, result.Source)
+		}
+
+		cr.addResult(result)
+		cr.follow(e.Request, link)
+	})
+
+	// find all JavaScript files
+	c.OnHTML("script[src]", func(e *colly.HTMLElement) {
+		if cr.allowLink(e.Request) {
+			cr.appendResult(e.Attr("src"), "script", e.Request)
+
+			// fetch the script to look inside it with -js
+			if cfg.JS {
+				cr.follow(e.Request, e.Attr("src"))
+			}
+		}
+	})
+
+	// with -js, record the URLs and paths quoted in scripts
+	if cfg.JS {
+		c.OnHTML(`script:not([src]):not([type="application/ld+json"])`, func(e *colly.HTMLElement) {
+			cr.extractJSURLs(e.Request, e.Text)
+		})
+
+		c.OnResponse(func(r *colly.Response) {
+			if isJavaScript(r) {
+				cr.extractJSURLs(r.Request, string(r.Body))
+			}
+		})
+	}
+
+	// find all the form action URLs, and the forms submitting to their page with -forms
+	c.OnHTML("form", func(e *colly.HTMLElement) {
+		if !cr.allowLink(e.Request) {
+			return
+		}
+
+		if _, ok := e.DOM.Attr("action"); ok || cfg.Forms {
+			cr.addResult(formResult(e))
+		}
+	})
+
+	// find the content embedded by iframes, frames, embeds and objects
+	c.OnHTML("iframe[src], frame[src], embed[src]", func(e *colly.HTMLElement) {
+		if !cr.allowLink(e.Request) {
+			return
+		}
+
+		link := e.Attr("src")
+		cr.appendResult(link, e.Name, e.Request)
+
+		if cfg.FollowIframes && (e.Name == "iframe" || e.Name == "frame") {
+			cr.follow(e.Request, link)
+		}
+	})
+
+	// follow the redirects of <meta http-equiv="refresh"> like links
+	c.OnHTML("meta[http-equiv][content]", func(e *colly.HTMLElement) {
+		if !strings.EqualFold(e.Attr("http-equiv"), "refresh") || !cr.allowLink(e.Request) {
+			return
+		}
+
+		if link := metaRefreshURL(e.Attr("content")); link != "" {
+			cr.appendResult(link, "meta-refresh", e.Request)
+			cr.follow(e.Request, link)
+		}
+	})
+
+	// record the base URL the links of the page are resolved against
+	c.OnHTML("base[href]", func(e *colly.HTMLElement) {
+		if cr.allowLink(e.Request) {
+			cr.appendResult(e.Attr("href"), "base", e.Request)
+		}
+	})
+
+	c.OnHTML("object[data]", func(e *colly.HTMLElement) {
+		if cr.allowLink(e.Request) {
+			cr.appendResult(e.Attr("data"), "object", e.Request)
+		}
+	})
+
+	// with -all, record stylesheets, icons, images and media sources too, without fetching them
+	if cfg.All {
+		for _, element := range []struct{ selector, attr string }{
+			{"link[href]", "href"},
+			{"img[src]", "src"},
+			{"source[src]", "src"},
+		} {
+			attr := element.attr
+
+			c.OnHTML(element.selector, func(e *colly.HTMLElement) {
+				if cr.allowLink(e.Request) {
+					cr.appendResult(e.Attr(attr), e.Name, e.Request)
+				}
+			})
+		}
+
+		// every candidate of responsive images
+		c.OnHTML("img[srcset], source[srcset]", func(e *colly.HTMLElement) {
+			if !cr.allowLink(e.Request) {
+				return
+			}
+
+			for _, link := range srcsetURLs(e.Attr("srcset")) {
+				cr.appendResult(link, "srcset", e.Request)
+			}
+		})
+
+		// and the links left in HTML comments, commented out markup included
+		c.OnHTML("html", func(e *colly.HTMLElement) {
+			if !cr.allowLink(e.Request) || len(e.DOM.Nodes) == 0 {
+				return
+			}
+
+			// comments can come before <html>
+			root := e.DOM.Nodes[0]
+			for root.Parent != nil {
+				root = root.Parent
+			}
+
+			for _, link := range commentURLs(root) {
+				cr.appendResult(link, "comment", e.Request)
+			}
+		})
+	}
+
+	// find the URLs of Link response headers, APIs paginate with them
+	c.OnResponse(func(r *colly.Response) {
+		cr.extractLinkHeaders(r)
+	})
+
+	// with -modules, follow module scripts and extract what they import
+	if cfg.Modules {
+		c.OnHTML("script[type=module][src]", func(e *colly.HTMLElement) {
+			if cr.allowLink(e.Request) {
+				cr.followModule(e.Request, e.Attr("src"))
+			}
+		})
+
+		// inline modules import relative to the page
+		c.OnHTML("script[type=module]:not([src])", func(e *colly.HTMLElement) {
+			cr.extractImports(e.Request, e.Text)
+		})
+
+		c.OnResponse(func(r *colly.Response) {
+			if cr.isModule(r.Request.URL.String()) {
+				cr.extractImports(r.Request, string(r.Body))
+			}
+		})
+	}
+
+	// with -manifests, follow web app manifests and service workers and record the URLs they list
+	if cfg.Manifests {
+		c.OnHTML(`link[rel~="manifest"][href]`, func(e *colly.HTMLElement) {
+			if cr.allowLink(e.Request) {
+				cr.appendResult(e.Attr("href"), "manifest", e.Request)
+				cr.followManifest(e.Request, e.Attr("href"), false)
+			}
+		})
+
+		c.OnHTML(`script:not([src])`, func(e *colly.HTMLElement) {
+			cr.extractServiceWorkers(e.Request, e.Text)
+		})
+
+		c.OnResponse(func(r *colly.Response) {
+			cr.extractManifest(r)
+
+			if isJavaScript(r) {
+				cr.extractServiceWorkers(r.Request, string(r.Body))
+			}
+		})
+	}
+
+	// with -structured-data, record the URLs of JSON-LD blocks and microdata
+	if cfg.StructuredData {
+		c.OnHTML(`script[type="application/ld+json"]`, func(e *colly.HTMLElement) {
+			cr.extractJSONLD(e.Request, e.Text)
+		})
+
+		c.OnHTML("[itemprop]", func(e *colly.HTMLElement) {
+			cr.extractMicrodata(e)
+		})
+	}
+}
+
+// recordResponses annotates the results with what the pages answered
+func (cr *crawl) recordResponses(c *colly.Collector) {
+	cfg := cr.cfg
+	results := cr.results
+
+	// with -detect-soft404, compare every page with its host's response to a missing page
+	if cfg.DetectSoft404 {
+		c.OnResponse(func(r *colly.Response) {
+			cr.checkSoft404(r)
+		})
+	}
+
+	// remember the hosts using HSTS if -hsts is present
+	if cfg.HSTS {
+		c.OnResponse(func(r *colly.Response) {
+			cr.hsts.observe(r)
+		})
+	}
+
+	// record the robots directives of every page if -robots-meta is present
+	if cfg.RobotsMeta {
+		c.OnResponse(func(r *colly.Response) {
+			if tag := strings.Join((*r.Headers)["X-Robots-Tag"], ", "); tag != "" {
+				results.annotate(r.Request.URL.String(), func(result *Result) {
+					result.XRobotsTag = tag
+				})
+			}
+		})
+
+		c.OnHTML("meta[name][content]", func(e *colly.HTMLElement) {
+			if strings.EqualFold(e.Attr("name"), "robots") {
+				content := e.Attr("content")
+				results.annotate(e.Request.URL.String(), func(result *Result) {
+					result.MetaRobots = joinDirectives(result.MetaRobots, content)
+				})
+			}
+		})
+	}
+
+	// hash the body of every page if -hash is present
+	if cfg.HashBodies {
+		c.OnResponse(func(r *colly.Response) {
+			hash := fmt.Sprintf("%x", sha256.Sum256(r.Body))
+			results.annotate(r.Request.URL.String(), func(result *Result) {
+				result.Hash = hash
+			})
+		})
+	}
+
+	// save the exchanges of the URLs matching -capture, error pages included
+	if cfg.Capture != nil {
+		c.OnResponse(func(r *colly.Response) {
+			cr.capture(r)
+		})
+
+		c.OnError(func(r *colly.Response, err error) {
+			cr.capture(r)
+		})
+	}
+
+	// make conditional requests if -validators is present
+	if cfg.Validators != nil {
+		if !cfg.ForceRefresh {
+			c.OnRequest(func(r *colly.Request) {
+				cfg.Validators.condition(r.URL.String(), r.Headers)
+			})
+		}
+
+		c.OnResponse(func(r *colly.Response) {
+			cfg.Validators.update(r.Request.URL.String(), r.Headers)
+		})
+
+		// colly reports 304 as an error, there's no body to extract links from
+		c.OnError(func(r *colly.Response, err error) {
+			if r.StatusCode == http.StatusNotModified {
+				results.annotate(r.Request.URL.String(), func(result *Result) {
+					result.Unchanged = true
+				})
+			}
+		})
+	}
+
+	// record what the visited pages answered if -show-status is present
+	if cfg.ShowStatus {
+		cr.tracker = &statusTracker{results: results}
+		cr.tracker.track(c)
+	}
+}
+
+// shapeRequests decides what the requests of the crawl carry and where its redirects go
+func (cr *crawl) shapeRequests(c *colly.Collector) {
+	cfg := cr.cfg
+
+	// follow redirects per -max-redirects and -follow-redirects, colly refuses the ones leaving its allowed domains first
+	c.RedirectHandler = cr.redirect
+
+	// cap the depth of noisy secondary hosts if -host-threshold is present
+	if cfg.HostThreshold > 0 {
+		c.OnRequest(func(r *colly.Request) {
+			cr.capHostDepth(r)
+		})
+	}
+
+	// pick a user agent per request, before the custom headers so that -h still wins
+	if cfg.RandomAgent {
+		c.OnRequest(func(r *colly.Request) {
+			r.Headers.Set("User-Agent", cr.userAgent())
+		})
+	}
+
+	// add the custom headers
+	if cr.headers != nil {
+		c.OnRequest(func(r *colly.Request) {
+			for header, value := range cr.headers {
+				r.Headers.Set(header, value)
+			}
+		})
+	}
+
+	// build a navigation chain if -auto-referer is present, the starting URL is requested directly
+	if cfg.AutoReferer {
+		c.OnRequest(func(r *colly.Request) {
+			if referer, ok := cr.referers.Load(r.URL.String()); ok {
+				r.Headers.Set("Referer", referer.(string))
+			}
+		})
+	}
+
+	// the page is done, forget how many links it had
+	if cfg.MaxLinks > 0 {
+		c.OnScraped(func(r *colly.Response) {
+			cr.forgetLinks(r.Request)
+		})
+	}
+}
+
+// renderPages renders the HTML pages in headless Chrome for -render, registered last so the other
+// handlers see the response and the HTML handlers see the rendered DOM instead. It returns the
+// function closing the browser
+func (cr *crawl) renderPages(c *colly.Collector) func() {
+	rd := newRenderer(cr, renderTimeout(cr.cfg.Timeout))
+
+	c.OnResponse(func(r *colly.Response) {
+		if !isHTML(r) {
+			return
+		}
+
+		html, xhrs, err := rd.render(r)
+		if err != nil {
+			if cr.cfg.Verbose {
+				cr.logf("Could not render %s: %v\n", r.Request.URL, err)
+			}
+			return
+		}
+
+		r.Body = []byte(html)
+
+		if cr.allowLink(r.Request) {
+			for _, xhr := range xhrs {
+				cr.appendResult(xhr, "xhr", r.Request)
+				cr.follow(r.Request, xhr)
+			}
+		}
+	})
+
+	return rd.close
+}
+
+// setTransport makes the collector and the crawl's own clients go through the transport chain,
+// it returns the cookie jar of the crawl
+func (cr *crawl) setTransport(c *colly.Collector) (*cookiejar.Jar, error) {
+	cfg := cr.cfg
+
+	// Skip TLS verification if -insecure flag is present, or trust the -cacert CA.
+	// HTTPS_PROXY/HTTP_PROXY are honored so crawls can go through an interception proxy
+	transport := &http.Transport{
+		Proxy:              http.ProxyFromEnvironment,
+		TLSClientConfig:    &tls.Config{InsecureSkipVerify: cfg.Insecure, RootCAs: cfg.RootCAs},
+		DisableCompression: cfg.RawBody,
+	}
+
+	// -proxy wins over the environment
+	if cfg.Proxy != "" {
+		proxies, err := ParseProxies(cfg.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = roundRobinProxy(proxies)
+	}
+
+	// bound the DNS lookups if -max-dns is present
+	if cfg.MaxDNS > 0 {
+		transport.DialContext = newLimitedDialer(cfg.MaxDNS).DialContext
+	}
+
+	// connect to the -host-override address instead of the target's host
+	if cfg.HostOverride != "" {
+		transport.DialContext = overrideHost(transport.DialContext, cr.hostname, cfg.HostOverride)
+	}
+
+	roundTripper := cr.transportChain(transport)
+
+	// pause after the requests of the collector with -delay and -random-delay, one pause shared by every host.
+	// The crawl's own requests (robots.txt, sitemaps, login, -render) aren't paused, like with colly's LimitRule
+	if cfg.Delay > 0 || cfg.RandomDelay > 0 {
+		c.WithTransport(newPauseTransport(cr.ctx, roundTripper, cfg.Threads, cfg.Delay, cfg.RandomDelay, cr.rng))
+	} else {
+		c.WithTransport(roundTripper)
+	}
+	c.SetRequestTimeout(0)
+
+	// the session cookies of a crawl are its own, every crawl has a new jar (starting with -cookie-file)
+	jar := newCookieJar(cfg.Cookies)
+	c.SetCookieJar(jar)
+	cr.client = &http.Client{Transport: roundTripper, Jar: jar}
+
+	// the crawl's own requests follow the redirect policy too
+	if cfg.MaxRedirects > 0 || cfg.FollowRedirects != "" {
+		cr.client.CheckRedirect = cr.checkRedirect
+	}
+	cr.archives = &http.Client{Transport: &timeoutTransport{next: transport, timeout: archiveTimeout}}
+
+	return jar, nil
+}
+
+// transportChain wraps transport in the round trippers of the features present, innermost first
+func (cr *crawl) transportChain(transport *http.Transport) http.RoundTripper {
+	cfg := cr.cfg
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	// bound every attempt on its own, the clients don't time out the whole exchange
+	var roundTripper http.RoundTripper = &timeoutTransport{next: transport, timeout: timeout}
+
+	// colly must not gunzip either with -raw-body
+	if cfg.RawBody {
+		roundTripper = &rawBodyTransport{next: roundTripper}
+	}
+
+	// count the requests waiting for their response if -stats or -metrics are present
+	if cfg.Metrics != nil {
+		roundTripper = &metricsTransport{next: roundTripper, metrics: cfg.Metrics}
+	}
+
+	// record the exchanges if -har-out is present, below -digest and -retries to keep every attempt
+	if cfg.HAROut != nil {
+		roundTripper = &harTransport{next: roundTripper, log: cfg.HAROut}
+	}
+
+	// answer Digest challenges if -digest is present
+	if cfg.DigestAuth != "" {
+		roundTripper = newDigestTransport(roundTripper, cfg.DigestAuth)
+	}
+
+	// report the certificates if -tls-info is present, and seed their SANs with -expand-sans
+	if cfg.OnTLSInfo != nil || cfg.ExpandSANs {
+		roundTripper = &tlsTransport{next: roundTripper, report: func(info TLSInfo) {
+			if cfg.OnTLSInfo != nil {
+				cfg.OnTLSInfo(info)
+			}
+
+			if cfg.ExpandSANs && !cfg.Exact {
+				cr.collectSANs(info)
+			}
+		}}
+	}
+
+	// let -h override the Host header
+	if len(cr.headers) > 0 {
+		roundTripper = &hostTransport{next: roundTripper}
+	}
+
+	// add the -append-param parameters on the wire only
+	if len(cfg.AppendParams) > 0 {
+		roundTripper = &paramTransport{next: roundTripper, params: cfg.AppendParams}
+	}
+
+	// retry transient failures if -retries is present
+	if cfg.Retries > 0 {
+		codes := cfg.RetryCodes
+		if codes == nil {
+			codes = defaultRetryCodes
+		}
+
+		retry := newRetryTransport(roundTripper, cfg.Retries, codes, cfg.RetryBackoff)
+
+		if cfg.Events != nil {
+			retry.onRetry = func(req *http.Request, attempt int, resp *http.Response, err error, wait time.Duration) {
+				e := Event{Event: "retry", URL: req.URL.String(), Attempt: attempt, Wait: wait.String()}
+
+				if err != nil {
+					e.Error = err.Error()
+				} else {
+					e.Status = resp.StatusCode
+				}
+
+				cr.emit(e)
+			}
+		}
+
+		retry.log = cfg.Log
+		roundTripper = retry
+	}
+
+	// spread the requests like -timing-profile says
+	if cfg.TimingProfile != nil {
+		roundTripper = &delayTransport{next: roundTripper, profile: cfg.TimingProfile, rng: cr.rng}
+	}
+
+	// keep the cookies the servers set if -cookie-file is present
+	if cfg.Cookies != nil {
+		roundTripper = &cookieTransport{next: roundTripper, store: cfg.Cookies}
+	}
+
+	// record the targets of the redirects -follow-redirects refuses
+	if cfg.FollowRedirects == "scope" || cfg.FollowRedirects == "none" {
+		roundTripper = &redirectTransport{next: roundTripper, cr: cr}
+	}
+
+	// refuse what -deny and -safe deny where colly can't, e.g. redirects
+	if len(cfg.DenyRegex) > 0 || cfg.SafeMode {
+		roundTripper = &denyTransport{next: roundTripper, cr: cr}
+	}
+
+	// colly has no request contexts, every exchange (retries and delays included) gets the crawl's
+	return &contextTransport{next: roundTripper, ctx: cr.ctx}
+}
+
+// startSession starts with the cookies of -cookie, then logs in if -login-url is present
+func (cr *crawl) startSession(jar *cookiejar.Jar, url string) {
+	if cr.cfg.RawCookies != "" {
+		if err := cr.setRawCookies(jar, url); err != nil {
+			cr.logf("Invalid cookies: %v\n", err)
+		}
+	}
+
+	if cr.cfg.LoginURL != "" {
+		if err := cr.login(url); err != nil {
+			cr.logf("Login to %s failed, crawling without a session: %v\n", cr.cfg.LoginURL, err)
+		}
+	}
+}
+
+// seeds returns the URLs the crawl starts with, url and the ones the seeding features find
+func (cr *crawl) seeds(c *colly.Collector, url string) []string {
+	cfg := cr.cfg
+
+	seeds := []string{url}
+
+	// read the sitemaps if -sitemap is present
+	if cfg.Sitemap && !cfg.Exact {
+		seeds = append(seeds, cr.sitemapSeeds(url)...)
+	}
+
+	// record the paths of robots.txt if -robots is present
+	if cfg.Robots && !cfg.Exact {
+		seeds = append(seeds, cr.robotsSeeds(url)...)
+	}
+
+	// look the target up in web archives if -archive is present
+	if len(cfg.Archive) > 0 && !cfg.Exact {
+		seeds = append(seeds, cr.archiveSeeds(url)...)
+	}
+
+	// probe the well-known manifests if -manifests is present
+	if cfg.Manifests && !cfg.Exact {
+		seeds = append(seeds, cr.manifestSeeds(url)...)
+	}
+
+	// continue from the browser session if -har is present
+	if cfg.HAR != nil && !cfg.Exact {
+		seeds = append(seeds, cr.harSeeds()...)
+
+		if cfg.HARCookies {
+			for origin, cookies := range cr.harCookies() {
+				c.SetCookies(origin, cookies)
+			}
+		}
+	}
+
+	return seeds
+}
+
+// run crawls from the seeds until there's nothing left, then crawls the SAN hosts found meanwhile
+func (cr *crawl) run(c *colly.Collector, seeds []string) {
+	// Start scraping, colly skips the seeds that were already visited
+	for _, seed := range seeds {
+		cr.visit(c, nil, seed, 0)
+	}
+
+	if cr.queue != nil {
+		cr.queue.run(cr.workers(), cr.visitQueued(c))
+	}
+
+	// Wait until threads are finished
+	c.Wait()
+
+	// crawl the SAN hosts found meanwhile, their own certificates can add more.
+	// colly reads its scope without locking, so it's only extended between rounds
+	for seeds := cr.takeSANSeeds(); len(seeds) > 0; seeds = cr.takeSANSeeds() {
+		for _, seed := range seeds {
+			if c.AllowedDomains != nil {
+				c.AllowedDomains = append(c.AllowedDomains, seed)
+			} else if c.URLFilters != nil {
+				c.URLFilters = append(c.URLFilters, subsFilter(seed))
+			}
+
+			cr.visit(c, nil, "https://"+seed+"/", 0)
+		}
+
+		if cr.queue != nil {
+			cr.queue.run(cr.workers(), cr.visitQueued(c))
+		}
+
+		c.Wait()
+	}
+}
+
+// finish returns the results of the crawl once it's over, and checkpoints it with -state
+func (cr *crawl) finish(target string) []Result {
+	cfg := cr.cfg
+	found := cr.results.list()
+
+	if cfg.DetectSoft404 {
+		suppressed := cr.soft404s.count()
+		if suppressed > 0 {
+			cr.logf("Suppressed %d soft-404 pages on %s\n", suppressed, cr.hostname)
+		}
+
+		found = cr.soft404s.filter(found)
+	}
+
+	// check the recorded URLs are live if -verify is present
+	if cfg.Verify || cfg.LiveOnly {
+		found = cr.verify(found)
+	}
+
+	if cfg.Verbose {
+		cr.logf("Peak goroutines on %s: %d\n", cr.hostname, cr.goroutines.get())
+	}
+
+	// the last checkpoint, a crawl that was stopped keeps the results as recorded
+	if cfg.State != nil {
+		if cr.complete() {
+			cr.checkpoint(target, found, true)
+		} else {
+			cr.checkpoint(target, cr.results.list(), false)
+		}
+	}
+
+	return found
+}
+
+// isTabnabbable reports whether an anchor opens a new window that gets a handle on its opener,
+// noreferrer implies noopener
+func isTabnabbable(target string, rel string) bool {
+	if !strings.EqualFold(target, "_blank") {
+		return false
+	}
+
+	for _, token := range strings.Fields(strings.ToLower(rel)) {
+		if token == "noopener" || token == "noreferrer" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// withDefault adds a header to the parsed custom headers unless they set it already
+func withDefault(headers map[string]string, header string, value string) map[string]string {
+	for name := range headers {
+		if strings.EqualFold(name, header) {
+			return headers
+		}
+	}
+
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	headers[header] = value
+
+	return headers
+}
+
+// subsFilter matches the URLs of a hostname and its subdomains, for -subs
+func subsFilter(hostname string) *regexp.Regexp {
+	return regexp.MustCompile(".*(\\.|\\/\\/)" + strings.ReplaceAll(hostname, ".", "\\.") + "((#|\\/|\\?).*)?")
+}
+
+// joinDirectives appends directives to a comma-separated list
+func joinDirectives(list string, directives string) string {
+	if list == "" {
+		return directives
+	}
+
+	return list + ", " + directives
+}
+
+// parseHeaders does validation of headers input and saves it to a formatted map.
+func parseHeaders(rawHeaders string) (map[string]string, error) {
+	headers := make(map[string]string)
+
+	if rawHeaders != "" {
+		// if headers is not valid
+		if !strings.Contains(rawHeaders, ":") {
+			return nil, errors.New("headers flag not formatted properly (no colon to separate header and value)")
+		}
+
+		// Split headers by two semi-colons to avoid splitting values
+		rawHeaders := strings.Split(rawHeaders, ";;")
+
+		for _, header := range rawHeaders {
+			var parts []string
+
+			if strings.Contains(header, ": ") {
+				// To avoid a space before its value
+				parts = strings.SplitN(header, ": ", 2)
+			} else if strings.Contains(header, ":") {
+				parts = strings.SplitN(header, ":", 2)
+			} else {
+				// Bad header
+				continue
+			}
+
+			// append processed header to headers
+			headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+
+	return headers, nil
+}
+
+// defaultScheme returns the scheme prepended to URLs without one for a -default-scheme
+func defaultScheme(scheme string) string {
+	if scheme == "https" || scheme == "auto" {
+		return "https"
+	}
+
+	return "http"
+}
+
+// fallbackToHTTP returns the http version of an https URL that can't be requested
+func (cr *crawl) fallbackToHTTP(link string) string {
+	res, err := cr.do("HEAD", link)
+
+	if err == nil {
+		res.Body.Close()
+		return link
+	}
+
+	return "http://" + strings.TrimPrefix(link, "https://")
+}
+
+// extractHostname() extracts the hostname from a URL and returns it
+func extractHostname(urlString string) (string, error) {
+	u, err := url.Parse(urlString)
+
+	if err != nil {
+		// if error occured
+		return "", err
+	}
+
+	return u.Hostname(), nil
+}
+
+// hostnameRe matches plausible hostnames and IPv4 addresses
+var hostnameRe = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?)*\.?$`)
+
+// IsTarget reports whether an input line looks like a URL or a host that can be crawled
+func IsTarget(line string) bool {
+	if strings.ContainsAny(line, " \t") {
+		return false
+	}
+
+	if !strings.Contains(line, "://") {
+		line = "http://" + line
+	}
+
+	u, err := url.Parse(line)
+
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+
+	// IPv6 addresses are bracketed
+	if strings.HasPrefix(u.Host, "[") {
+		return strings.Contains(u.Host, "]")
+	}
+
+	return hostnameRe.MatchString(u.Hostname())
+}
+
+// TargetHost returns the lowercased hostname of an input URL, schemes are optional
+func TargetHost(target string) string {
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || u.Hostname() == "" {
+		return "invalid"
+	}
+
+	return strings.ToLower(u.Hostname())
+}
+
+// ReadList reads a file of one entry per line, skipping blank lines and # comments
+func ReadList(path string) ([]string, error) {
+	f, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	entries := make([]string, 0)
+	s := bufio.NewScanner(f)
+
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+
+		if line != "" && !strings.HasPrefix(line, "#") {
+			entries = append(entries, line)
+		}
+	}
+
+	return entries, s.Err()
+}
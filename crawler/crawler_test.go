@@ -0,0 +1,50 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaultScheme(t *testing.T) {
+	// a plain HTTP server, https requests to it fail
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><a href="/page">page</a></body></html>`))
+	}))
+	defer server.Close()
+
+	schemeless := strings.TrimPrefix(server.URL, "http://")
+
+	tests := []struct {
+		name   string
+		scheme string
+		want   string
+	}{
+		{"unset", "", server.URL + "/page"},
+		{"http", "http", server.URL + "/page"},
+		{"https", "https", ""},
+		{"auto falls back to http", "auto", server.URL + "/page"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Threads: 1, Depth: 1, SubsInScope: true, IgnoreRobots: true, DefaultScheme: tt.scheme, Timeout: 2 * time.Second}
+
+			var found []string
+			for _, result := range StartCrawler(schemeless, cfg) {
+				found = append(found, result.URL)
+			}
+
+			if tt.want == "" && len(found) > 0 {
+				t.Errorf("-default-scheme %q found %v, want nothing", tt.scheme, found)
+			}
+
+			if tt.want != "" && (len(found) != 1 || found[0] != tt.want) {
+				t.Errorf("-default-scheme %q found %v, want [%s]", tt.scheme, found, tt.want)
+			}
+		})
+	}
+}
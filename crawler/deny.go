@@ -0,0 +1,121 @@
+package crawler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/gocolly/colly"
+)
+
+// safeModeRe matches the paths (and queries) of the links that end the session or destroy data when they're requested.
+// The words must be whole path segments, query keys or values (or parts of them split by - _ and .),
+// so /blog/removed-features or ?q=deleted are crawled
+var safeModeRe = regexp.MustCompile(`(?i)(^|[/?&=_-])(log[-_]?out|log[-_]?off|sign[-_]?out|sign[-_]?off|delete|destroy|remove|unsubscribe|deactivate|revoke|close[-_]?account)($|[/?&=._-])`)
+
+// errDenied is the error of the requests -deny and -safe refuse
+var errDenied = errors.New("denied by -deny or -safe")
+
+// ParseDenyList compiles the comma-separated regexes of -deny, e.g. "/admin/,[?&]action=(drop|purge)".
+// Commas inside brackets, braces and parentheses belong to their regex, like in \d{1,3}
+func ParseDenyList(list string) ([]*regexp.Regexp, error) {
+	regexes := make([]*regexp.Regexp, 0)
+
+	depth, start := 0, 0
+	escaped := false
+
+	add := func(expr string) error {
+		if strings.TrimSpace(expr) == "" {
+			return nil
+		}
+
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return err
+		}
+
+		regexes = append(regexes, re)
+		return nil
+	}
+
+	for i, ch := range list {
+		switch {
+		case escaped:
+			escaped = false
+		case ch == '\\':
+			escaped = true
+		case ch == '[' || ch == '{' || ch == '(':
+			depth++
+		case (ch == ']' || ch == '}' || ch == ')') && depth > 0:
+			depth--
+		case ch == ',' && depth == 0:
+			if err := add(list[start:i]); err != nil {
+				return nil, err
+			}
+			start = i + 1
+		}
+	}
+
+	if err := add(list[start:]); err != nil {
+		return nil, err
+	}
+
+	return regexes, nil
+}
+
+// denied reports whether link must never be requested, with -deny or -safe
+func (cr *crawl) denied(link string) bool {
+	for _, re := range cr.cfg.DenyRegex {
+		if re.MatchString(link) {
+			return true
+		}
+	}
+
+	if !cr.cfg.SafeMode {
+		return false
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+
+	return safeModeRe.MatchString(u.EscapedPath() + "?" + u.RawQuery)
+}
+
+// deny aborts the colly requests of denied links, they stay recorded
+func (cr *crawl) deny(r *colly.Request) {
+	link := r.URL.String()
+
+	if !cr.denied(link) {
+		return
+	}
+
+	if cr.cfg.Verbose {
+		if _, loaded := cr.denyReported.LoadOrStore(link, true); !loaded {
+			cr.logf("Denied: %s\n", link)
+		}
+	}
+
+	cr.skip(r)
+}
+
+// denyTransport refuses the requests colly doesn't see: redirects, and the requests of -verify and the like
+type denyTransport struct {
+	next http.RoundTripper
+	cr   *crawl
+}
+
+func (t *denyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.cr.denied(req.URL.String()) {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+
+		return nil, errDenied
+	}
+
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,33 @@
+package crawler
+
+import "testing"
+
+func TestSafeModeDenied(t *testing.T) {
+	cr := &crawl{cfg: &Config{SafeMode: true}}
+
+	for link, want := range map[string]bool{
+		"https://x.com/logout":                 true,
+		"https://x.com/account/Log-Out/":       true,
+		"https://x.com/auth/sign_out?next=/":   true,
+		"https://x.com/user/delete.php?id=1":   true,
+		"https://x.com/items/1/remove":         true,
+		"https://x.com/settings/close-account": true,
+		"https://x.com/do?action=destroy&id=1": true,
+		"https://x.com/do?unsubscribe=1":       true,
+		"https://x.com/api/token-revoke":       true,
+		"https://x.com/blog/removed-features":  false,
+		"https://x.com/search?q=deleted":       false,
+		"https://x.com/designers/signoffice":   false,
+		"https://x.com/docs/deletion-policy":   false,
+		"https://x.com/":                       false,
+	} {
+		if got := cr.denied(link); got != want {
+			t.Errorf("denied(%q) = %v, want %v", link, got, want)
+		}
+	}
+
+	cr.cfg.SafeMode = false
+	if cr.denied("https://x.com/logout") {
+		t.Error("logout links are requested without safe mode")
+	}
+}
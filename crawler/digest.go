@@ -0,0 +1,223 @@
+package crawler
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// digestTransport answers HTTP Digest challenges (RFC 7616) with the -digest credentials.
+// Once a host challenged, its later requests are authorized upfront with the same nonce
+type digestTransport struct {
+	next     http.RoundTripper
+	username string
+	password string
+
+	mu         sync.Mutex
+	challenges map[string]*digestChallenge
+}
+
+// digestChallenge is a parsed WWW-Authenticate: Digest header and the number of requests made with its nonce
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       bool
+	count     int
+}
+
+func newDigestTransport(next http.RoundTripper, credentials string) *digestTransport {
+	username, password, _ := strings.Cut(credentials, ":")
+
+	return &digestTransport{
+		next:       next,
+		username:   username,
+		password:   password,
+		challenges: make(map[string]*digestChallenge),
+	}
+}
+
+func (t *digestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var resp *http.Response
+	var err error
+
+	if authorized, ok := t.authorize(req); ok {
+		resp, err = t.next.RoundTrip(authorized)
+	} else {
+		resp, err = t.next.RoundTrip(req)
+	}
+
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	challenge := parseDigestChallenge(resp.Header.Values("WWW-Authenticate"))
+
+	// a body can only be sent again if it can be rewound
+	if challenge == nil || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		return resp, err
+	}
+
+	t.mu.Lock()
+	t.challenges[req.URL.Host] = challenge
+	t.mu.Unlock()
+
+	authorized, ok := t.authorize(req)
+
+	if !ok {
+		return resp, err
+	}
+
+	if req.GetBody != nil {
+		if authorized.Body, err = req.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	resp, err = t.next.RoundTrip(authorized)
+
+	// report the request as it was made by colly
+	if resp != nil {
+		resp.Request = req
+	}
+
+	return resp, err
+}
+
+// authorize returns a copy of req answering the last challenge of its host, if there's one
+func (t *digestTransport) authorize(req *http.Request) (*http.Request, bool) {
+	t.mu.Lock()
+	shared, ok := t.challenges[req.URL.Host]
+
+	if !ok {
+		t.mu.Unlock()
+		return nil, false
+	}
+
+	// every request with a nonce needs its own count
+	shared.count++
+	challenge := *shared
+	t.mu.Unlock()
+
+	newHash := digestHash(challenge.algorithm)
+
+	if newHash == nil {
+		return nil, false
+	}
+
+	h := func(s string) string {
+		sum := newHash()
+		sum.Write([]byte(s))
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+
+	cnonce := randomHex(16)
+	nc := fmt.Sprintf("%08x", challenge.count)
+	uri := req.URL.RequestURI()
+
+	ha1 := h(t.username + ":" + challenge.realm + ":" + t.password)
+	if strings.HasSuffix(strings.ToLower(challenge.algorithm), "-sess") {
+		ha1 = h(ha1 + ":" + challenge.nonce + ":" + cnonce)
+	}
+
+	ha2 := h(req.Method + ":" + uri)
+
+	var response string
+	if challenge.qop {
+		response = h(strings.Join([]string{ha1, challenge.nonce, nc, cnonce, "auth", ha2}, ":"))
+	} else {
+		response = h(ha1 + ":" + challenge.nonce + ":" + ha2)
+	}
+
+	header := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s", response="%s"`,
+		t.username, challenge.realm, challenge.nonce, uri, response)
+
+	if challenge.algorithm != "" {
+		header += ", algorithm=" + challenge.algorithm
+	}
+
+	if challenge.qop {
+		header += fmt.Sprintf(`, qop=auth, nc=%s, cnonce="%s"`, nc, cnonce)
+	}
+
+	if challenge.opaque != "" {
+		header += fmt.Sprintf(`, opaque="%s"`, challenge.opaque)
+	}
+
+	out := req.Clone(req.Context())
+	out.Header.Set("Authorization", header)
+
+	return out, true
+}
+
+// parseDigestChallenge returns the first Digest challenge of WWW-Authenticate headers
+func parseDigestChallenge(headers []string) *digestChallenge {
+	for _, header := range headers {
+		scheme, params, ok := strings.Cut(strings.TrimSpace(header), " ")
+
+		if !ok || !strings.EqualFold(scheme, "Digest") {
+			continue
+		}
+
+		challenge := &digestChallenge{}
+
+		for _, param := range splitOutside(params, ',') {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			value = strings.Trim(strings.TrimSpace(value), `"`)
+
+			switch strings.ToLower(key) {
+			case "realm":
+				challenge.realm = value
+			case "nonce":
+				challenge.nonce = value
+			case "opaque":
+				challenge.opaque = value
+			case "algorithm":
+				challenge.algorithm = value
+			case "qop":
+				for _, qop := range strings.Split(value, ",") {
+					if strings.TrimSpace(qop) == "auth" {
+						challenge.qop = true
+					}
+				}
+			}
+		}
+
+		if challenge.nonce != "" {
+			return challenge
+		}
+	}
+
+	return nil
+}
+
+// digestHash returns the hash function of a Digest algorithm, nil for unsupported ones
+func digestHash(algorithm string) func() hash.Hash {
+	switch strings.ToUpper(strings.TrimSuffix(strings.ToLower(algorithm), "-sess")) {
+	case "", "MD5":
+		return md5.New
+	case "SHA-256":
+		return sha256.New
+	}
+
+	return nil
+}
+
+// randomHex returns n random bytes hex-encoded
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,156 @@
+package crawler
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// digestServer is a Digest-protected fixture (MD5, qop=auth) accepting user:secret
+type digestServer struct {
+	mu         sync.Mutex
+	challenges int
+	authorized int
+	bodies     []string
+}
+
+func (s *digestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	params := parseAuthorization(r.Header.Get("Authorization"))
+
+	if params == nil || !validDigest(r, params, "user", "secret") {
+		s.challenges++
+		w.Header().Set("WWW-Authenticate", `Digest realm="fixture", nonce="abc123", opaque="xyz", qop="auth", algorithm=MD5`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	s.authorized++
+	body, _ := io.ReadAll(r.Body)
+	s.bodies = append(s.bodies, string(body))
+
+	fmt.Fprint(w, "welcome")
+}
+
+// parseAuthorization returns the parameters of an Authorization: Digest header, nil for other headers
+func parseAuthorization(header string) map[string]string {
+	scheme, rest, ok := strings.Cut(header, " ")
+	if !ok || scheme != "Digest" {
+		return nil
+	}
+
+	params := make(map[string]string)
+	for _, param := range strings.Split(rest, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		params[key] = strings.Trim(value, `"`)
+	}
+
+	return params
+}
+
+func validDigest(r *http.Request, params map[string]string, username string, password string) bool {
+	md5hex := func(s string) string {
+		sum := md5.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+
+	if params["username"] != username || params["nonce"] != "abc123" || params["opaque"] != "xyz" || params["uri"] != r.URL.RequestURI() {
+		return false
+	}
+
+	ha1 := md5hex(username + ":fixture:" + password)
+	ha2 := md5hex(r.Method + ":" + params["uri"])
+
+	return params["response"] == md5hex(strings.Join([]string{ha1, "abc123", params["nc"], params["cnonce"], "auth", ha2}, ":"))
+}
+
+func TestDigestTransport(t *testing.T) {
+	fixture := &digestServer{}
+	server := httptest.NewServer(fixture)
+	defer server.Close()
+
+	client := &http.Client{Transport: newDigestTransport(http.DefaultTransport, "user:secret")}
+
+	// the first request is challenged and retried with the answer
+	resp, err := client.Get(server.URL + "/a?x=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || string(body) != "welcome" {
+		t.Fatalf("got %d %q, want 200 \"welcome\"", resp.StatusCode, body)
+	}
+
+	if resp.Request.Header.Get("Authorization") != "" {
+		t.Error("the response should report the request as it was made, without the Authorization header")
+	}
+
+	// the next ones answer the same challenge upfront, bodies are sent again when they had to be
+	resp, err = client.Get(server.URL + "/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	resp, err = client.Post(server.URL+"/c", "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if fixture.challenges != 1 || fixture.authorized != 3 {
+		t.Errorf("got %d challenges and %d authorized requests, want 1 and 3", fixture.challenges, fixture.authorized)
+	}
+
+	if fixture.bodies[2] != "payload" {
+		t.Errorf("POST body = %q, want \"payload\"", fixture.bodies[2])
+	}
+}
+
+func TestDigestTransportWrongPassword(t *testing.T) {
+	fixture := &digestServer{}
+	server := httptest.NewServer(fixture)
+	defer server.Close()
+
+	client := &http.Client{Transport: newDigestTransport(http.DefaultTransport, "user:wrong")}
+
+	resp, err := client.Get(server.URL + "/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// a rejected answer isn't retried forever
+	if resp.StatusCode != http.StatusUnauthorized || fixture.challenges != 2 {
+		t.Errorf("got %d after %d challenges, want 401 after 2", resp.StatusCode, fixture.challenges)
+	}
+}
+
+func TestParseDigestChallenge(t *testing.T) {
+	challenge := parseDigestChallenge([]string{
+		`Basic realm="other"`,
+		`Digest realm="a, b", nonce="n", qop="auth-int, auth", algorithm=SHA-256`,
+	})
+
+	if challenge == nil {
+		t.Fatal("no challenge parsed")
+	}
+
+	if challenge.realm != "a, b" || challenge.nonce != "n" || !challenge.qop || challenge.algorithm != "SHA-256" {
+		t.Errorf("got %+v", *challenge)
+	}
+
+	if parseDigestChallenge([]string{`Basic realm="x"`}) != nil {
+		t.Error("a Basic challenge isn't a Digest one")
+	}
+}
@@ -0,0 +1,106 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+)
+
+// dialFunc is the DialContext of a transport
+type dialFunc func(ctx context.Context, network string, addr string) (net.Conn, error)
+
+// limitedDialer bounds the number of concurrent DNS lookups (-max-dns),
+// large -subs crawls would flood the resolver otherwise
+type limitedDialer struct {
+	dialer  *net.Dialer
+	lookups chan struct{}
+}
+
+func newLimitedDialer(maxLookups int) *limitedDialer {
+	return &limitedDialer{
+		dialer:  &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		lookups: make(chan struct{}, maxLookups),
+	}
+}
+
+// DialContext resolves the host of addr within the lookup limit, then dials its addresses in turn
+func (d *limitedDialer) DialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+
+	if err != nil || net.ParseIP(host) != nil {
+		return d.dialer.DialContext(ctx, network, addr)
+	}
+
+	ips, err := d.lookup(ctx, host)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ip := range ips {
+		var conn net.Conn
+
+		if conn, err = d.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port)); err == nil {
+			return conn, nil
+		}
+	}
+
+	return nil, err
+}
+
+// ParseHostOverride validates a -host-override address, an IP or hostname with an optional port
+func ParseHostOverride(addr string) (string, error) {
+	addr = strings.TrimSpace(addr)
+
+	if strings.Contains(addr, "/") {
+		return "", errors.New("the address takes no scheme nor path, e.g. 203.0.113.7 or 203.0.113.7:8443")
+	}
+
+	host := addr
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		host = h
+	}
+
+	if host == "" {
+		return "", errors.New("missing host in " + addr)
+	}
+
+	return addr, nil
+}
+
+// overrideHost dials addr instead of hostname, on the port of the request unless addr has one.
+// The URLs, the Host header and the TLS server name stay hostname's
+func overrideHost(dial dialFunc, hostname string, addr string) dialFunc {
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	}
+
+	return func(ctx context.Context, network string, target string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(target)
+
+		if err != nil || !strings.EqualFold(strings.TrimSuffix(host, "."), hostname) {
+			return dial(ctx, network, target)
+		}
+
+		if _, _, err := net.SplitHostPort(addr); err == nil {
+			return dial(ctx, network, addr)
+		}
+
+		return dial(ctx, network, net.JoinHostPort(strings.Trim(addr, "[]"), port))
+	}
+}
+
+// lookup resolves host once a lookup slot is free
+func (d *limitedDialer) lookup(ctx context.Context, host string) ([]net.IPAddr, error) {
+	select {
+	case d.lookups <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	defer func() { <-d.lookups }()
+
+	return net.DefaultResolver.LookupIPAddr(ctx, host)
+}
@@ -0,0 +1,43 @@
+package crawler
+
+import (
+	"sync/atomic"
+
+	"github.com/gocolly/colly"
+)
+
+// EstimateRequests crawls only the starting page of url and extrapolates how many requests a
+// crawl of cfg.Depth would make, assuming every page has as many new links to follow as that one.
+// It returns the number of links found to follow and the estimate
+func EstimateRequests(url string, cfg *Config) (int, float64) {
+	var links int32
+
+	shallow := *cfg
+
+	// let colly check the scope, depth and duplicates of the links on the starting page,
+	// then count the requests it would make instead of making them
+	shallow.Depth = 2
+	shallow.beforeRequest = func(r *colly.Request) {
+		if r.Depth > 1 {
+			atomic.AddInt32(&links, 1)
+			r.Abort()
+		}
+	}
+
+	StartCrawler(url, &shallow)
+
+	return int(links), extrapolateRequests(float64(links), cfg.Depth)
+}
+
+// extrapolateRequests sums the pages of every level of a crawl with a constant branching factor:
+// 1 + b + b^2 + ... + b^(depth-1)
+func extrapolateRequests(branching float64, depth int) float64 {
+	total, level := 0.0, 1.0
+
+	for i := 0; i < depth; i++ {
+		total += level
+		level *= branching
+	}
+
+	return total
+}
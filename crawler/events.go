@@ -0,0 +1,94 @@
+package crawler
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Event is a line of the -events-out stream
+type Event struct {
+	Time time.Time `json:"time"`
+
+	// visit, found, error or retry
+	Event string `json:"event"`
+
+	// the host of the starting URL of the crawl
+	Target string `json:"target"`
+
+	URL    string `json:"url"`
+	Source string `json:"source,omitempty"`
+	Type   string `json:"type,omitempty"`
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+
+	// for retries, the number of the retry and how long it waits
+	Attempt int    `json:"attempt,omitempty"`
+	Wait    string `json:"wait,omitempty"`
+}
+
+// eventBuffer is how many events can wait for the writer before new ones are dropped
+const eventBuffer = 4096
+
+// EventLog writes events as NDJSON from its own goroutine, the crawl never waits for it
+type EventLog struct {
+	events  chan Event
+	done    sync.WaitGroup
+	dropped int64
+}
+
+func NewEventLog(w io.Writer) *EventLog {
+	l := &EventLog{events: make(chan Event, eventBuffer)}
+	l.done.Add(1)
+
+	go func() {
+		defer l.done.Done()
+
+		bw := bufio.NewWriter(w)
+		enc := json.NewEncoder(bw)
+		enc.SetEscapeHTML(false)
+
+		for e := range l.events {
+			enc.Encode(e)
+
+			// flush whenever the stream catches up, so dashboards see events live
+			if len(l.events) == 0 {
+				bw.Flush()
+			}
+		}
+
+		bw.Flush()
+	}()
+
+	return l
+}
+
+// Emit queues an event, it's dropped when the writer is too far behind
+func (l *EventLog) Emit(e Event) {
+	e.Time = time.Now()
+
+	select {
+	case l.events <- e:
+	default:
+		atomic.AddInt64(&l.dropped, 1)
+	}
+}
+
+// Close writes the queued events and returns how many were dropped
+func (l *EventLog) Close() int64 {
+	close(l.events)
+	l.done.Wait()
+
+	return atomic.LoadInt64(&l.dropped)
+}
+
+// emit sends an event of the crawl if -events-out is present
+func (cr *crawl) emit(e Event) {
+	if cr.cfg.Events != nil {
+		e.Target = cr.hostname
+		cr.cfg.Events.Emit(e)
+	}
+}
@@ -0,0 +1,52 @@
+package crawler
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/gocolly/colly"
+)
+
+// fail stops the crawl on its first error with -fail-fast, requests in flight still finish
+func (cr *crawl) fail(r *colly.Response, err error) {
+	if !atomic.CompareAndSwapInt32(&cr.failed, 0, 1) {
+		return
+	}
+
+	status := ""
+	if r.StatusCode != 0 {
+		status = fmt.Sprintf(" (status %d)", r.StatusCode)
+	}
+
+	cr.logf("Stopping the crawl of %s on the first error: %s: %v%s\n", cr.hostname, r.Request.URL, err, status)
+}
+
+// cancelled reports whether the context of the crawl is done, with -max-time or -batch-maxtime
+func (cr *crawl) cancelled() bool {
+	return cr.ctx.Err() != nil
+}
+
+// spend counts a request against -max-requests and aborts the ones past the budget
+func (cr *crawl) spend(r *colly.Request) {
+	if cr.overBudget() {
+		r.Abort()
+	}
+}
+
+// overBudget counts a request against -max-requests and reports whether it's past the budget
+func (cr *crawl) overBudget() bool {
+	spent := atomic.AddInt64(&cr.requests, 1)
+
+	if spent == int64(cr.cfg.MaxRequests)+1 {
+		cr.logf("Stopping the crawl of %s after %d requests\n", cr.hostname, cr.cfg.MaxRequests)
+	}
+
+	return spent > int64(cr.cfg.MaxRequests)
+}
+
+// stopIfFailed aborts the requests made after the crawl failed
+func (cr *crawl) stopIfFailed(r *colly.Request) {
+	if atomic.LoadInt32(&cr.failed) != 0 {
+		r.Abort()
+	}
+}
@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/gocolly/colly"
+)
+
+// Input is a named field of a form, what it submits
+type Input struct {
+	Name string `json:"name"`
+
+	// The type of an input or a button (text when missing), or select or textarea
+	Type string `json:"type"`
+}
+
+// formResult builds the result of a form with its method and inputs.
+// A form without action submits to its page
+func formResult(e *colly.HTMLElement) Result {
+	result := newResult(e.Attr("action"), "form", e.Request)
+
+	if strings.TrimSpace(e.Attr("action")) == "" {
+		result.URL = e.Request.URL.String()
+	}
+
+	result.Method = strings.ToUpper(strings.TrimSpace(e.Attr("method")))
+	if result.Method == "" {
+		result.Method = "GET"
+	}
+
+	e.ForEach("input[name], select[name], textarea[name], button[name]", func(_ int, field *colly.HTMLElement) {
+		input := Input{Name: field.Attr("name"), Type: strings.ToLower(field.Attr("type"))}
+
+		switch {
+		case field.Name == "select" || field.Name == "textarea":
+			input.Type = field.Name
+		case input.Type == "" && field.Name == "button":
+			input.Type = "submit"
+		case input.Type == "":
+			input.Type = "text"
+		}
+
+		result.Inputs = append(result.Inputs, input)
+		result.Params = append(result.Params, input.Name)
+	})
+
+	return result
+}
+
+// paramNames merges the names of the query parameters of link into names, sorted and deduplicated
+func paramNames(link string, names []string) []string {
+	if u, err := url.Parse(link); err == nil {
+		for name := range u.Query() {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	sort.Strings(names)
+
+	unique := names[:1]
+	for _, name := range names[1:] {
+		if name != unique[len(unique)-1] {
+			unique = append(unique, name)
+		}
+	}
+
+	return unique
+}
@@ -0,0 +1,79 @@
+package crawler
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+)
+
+// GoroutineLimit is the ceiling of -max-goroutines shared by the crawls of a run: the crawls running
+// at once (-c, the jobs of -serve) visit their links with the same slots. It's safe for concurrent use
+type GoroutineLimit struct {
+	slots chan struct{}
+}
+
+// NewGoroutineLimit returns a ceiling of n goroutines visiting links at once
+func NewGoroutineLimit(n int) *GoroutineLimit {
+	return &GoroutineLimit{slots: make(chan struct{}, max(n, 1))}
+}
+
+// acquire waits for a slot, it returns false without one once ctx is done
+func (l *GoroutineLimit) acquire(ctx context.Context) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (l *GoroutineLimit) release() {
+	<-l.slots
+}
+
+// limited runs work in a slot of the crawl's ceiling, if it has one. A cancelled crawl runs it without,
+// its requests are aborted anyway
+func (cr *crawl) limited(work func()) {
+	if cr.slots != nil && cr.slots.acquire(cr.ctx) {
+		defer cr.slots.release()
+	}
+
+	work()
+}
+
+// workers returns the number of goroutines of a crawl visiting links, the threads capped at -max-goroutines.
+// They visit in the slots of the ceiling shared with the other crawls
+func (cr *crawl) workers() int {
+	workers := cr.cfg.Threads
+
+	if cr.cfg.MaxGoroutines > 0 && workers > cr.cfg.MaxGoroutines {
+		workers = cr.cfg.MaxGoroutines
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	return workers
+}
+
+// goroutinePeak tracks the highest goroutine count seen by the requests of a crawl, for -verbose
+type goroutinePeak struct {
+	peak int64
+}
+
+// sample records the current goroutine count if it's a new peak
+func (g *goroutinePeak) sample() {
+	n := int64(runtime.NumGoroutine())
+
+	for {
+		peak := atomic.LoadInt64(&g.peak)
+		if n <= peak || atomic.CompareAndSwapInt64(&g.peak, peak, n) {
+			return
+		}
+	}
+}
+
+func (g *goroutinePeak) get() int64 {
+	return atomic.LoadInt64(&g.peak)
+}
@@ -0,0 +1,58 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGoroutineLimitShared(t *testing.T) {
+	var inFlight, peak atomic.Int32
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+
+		time.Sleep(50 * time.Millisecond)
+
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			for i := 0; i < 4; i++ {
+				fmt.Fprintf(w, `<a href="/%d">%d</a>`, i, i)
+			}
+		}
+	})
+
+	first, second := httptest.NewServer(handler), httptest.NewServer(handler)
+	defer first.Close()
+	defer second.Close()
+
+	// two targets crawled at once, as with -c 2, under a ceiling of 2 for both
+	cfg := &Config{Threads: 4, Depth: 2, SubsInScope: true, IgnoreRobots: true, Timeout: 2 * time.Second, MaxGoroutines: 2}
+	cfg.Goroutines = NewGoroutineLimit(cfg.MaxGoroutines)
+
+	var wg sync.WaitGroup
+	for _, target := range []string{first.URL + "/", second.URL + "/"} {
+		wg.Add(1)
+		go func(target string) {
+			defer wg.Done()
+			StartCrawler(target, cfg)
+		}(target)
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got == 0 || got > 2 {
+		t.Errorf("%d requests at once, want at most the shared ceiling of 2", got)
+	}
+}
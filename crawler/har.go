@@ -0,0 +1,120 @@
+package crawler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// HARSession holds the requests of a browser session recorded as a HAR file, for -har
+type HARSession struct {
+	// the unique http(s) URLs of the GET requests, in recording order
+	URLs []string
+
+	// scheme://host/ => the last value of each cookie the browser sent there
+	cookies map[string]map[string]string
+}
+
+// harFile is the part of the HAR format the session is read from
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				Method  string `json:"method"`
+				URL     string `json:"url"`
+				Cookies []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"cookies"`
+			} `json:"request"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// LoadHAR reads the requests of a HAR file
+func LoadHAR(path string) (*HARSession, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, err
+	}
+
+	session := &HARSession{cookies: make(map[string]map[string]string)}
+	seen := make(map[string]bool)
+
+	for _, entry := range har.Log.Entries {
+		req := entry.Request
+		u, err := url.Parse(req.URL)
+
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+
+		// later requests carry the fresher cookies
+		origin := u.Scheme + "://" + u.Host + "/"
+		for _, cookie := range req.Cookies {
+			if session.cookies[origin] == nil {
+				session.cookies[origin] = make(map[string]string)
+			}
+			session.cookies[origin][cookie.Name] = cookie.Value
+		}
+
+		// submissions are recorded by the browser, not replayed
+		if req.Method != "" && !strings.EqualFold(req.Method, "GET") {
+			continue
+		}
+
+		link := lowerHost(u.String())
+		if !seen[link] {
+			seen[link] = true
+			session.URLs = append(session.URLs, link)
+		}
+	}
+
+	return session, nil
+}
+
+// harSeeds returns the URLs of the session the crawl would follow from its starting URL
+func (cr *crawl) harSeeds() []string {
+	seeds := make([]string, 0)
+
+	for _, link := range cr.cfg.HAR.URLs {
+		if cr.seedable(link) {
+			seeds = append(seeds, link)
+		}
+	}
+
+	return seeds
+}
+
+// seedable reports whether a URL found outside the crawl (in a HAR file, a sitemap) can start it
+func (cr *crawl) seedable(link string) bool {
+	if cr.isSkippedHost(link) || (len(cr.cfg.Ports) > 0 && !portAllowed(link, cr.cfg.Ports)) {
+		return false
+	}
+
+	return cr.inScope(link, 0)
+}
+
+// harCookies returns the cookies of the session per in-scope origin, ready for the collector's jar
+func (cr *crawl) harCookies() map[string][]*http.Cookie {
+	jar := make(map[string][]*http.Cookie)
+
+	for origin, values := range cr.cfg.HAR.cookies {
+		if !cr.inScope(origin, 0) {
+			continue
+		}
+
+		for name, value := range values {
+			jar[origin] = append(jar[origin], &http.Cookie{Name: name, Value: value})
+		}
+	}
+
+	return jar
+}
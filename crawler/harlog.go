@@ -0,0 +1,278 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// harMaxBody is the part of a body kept in the archive, larger bodies are cut
+const harMaxBody = 5 << 20
+
+// HARLog writes every exchange of the crawls to a HAR archive as it completes, for -har-out.
+// Burp, ZAP and the browser devtools import it
+type HARLog struct {
+	mu      sync.Mutex
+	w       *bufio.Writer
+	entries int
+	err     error
+}
+
+// harEntry and the types below are the part of HAR 1.2 the archive fills in
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// NewHARLog starts the archive on w, Close ends it
+func NewHARLog(w io.Writer) *HARLog {
+	l := &HARLog{w: bufio.NewWriter(w)}
+	_, l.err = l.w.WriteString(`{"log":{"version":"1.2","creator":{"name":"RockRawler","version":"1.0"},"pages":[],"entries":[` + "\n")
+
+	return l
+}
+
+// add appends an entry to the archive
+func (l *HARLog) add(entry harEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err != nil {
+		return
+	}
+
+	if l.entries > 0 {
+		l.w.WriteString(",\n")
+	}
+	l.entries++
+
+	_, l.err = l.w.Write(data)
+}
+
+// Close ends the archive, it returns the first write error
+func (l *HARLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err == nil {
+		_, l.err = l.w.WriteString("\n]}}\n")
+	}
+
+	if l.err == nil {
+		l.err = l.w.Flush()
+	}
+
+	return l.err
+}
+
+// harTransport records the exchanges in the HARLog once their body was read, retries and the
+// challenges of -digest included. Exchanges that got no response aren't recorded
+type harTransport struct {
+	next http.RoundTripper
+	log  *HARLog
+}
+
+func (t *harTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	started := time.Now()
+	entry := harEntry{StartedDateTime: started.Format(time.RFC3339Nano), Request: harRequestOf(req)}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	headers := time.Now()
+
+	entry.Response = harResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Cookies:     harCookies(resp.Cookies()),
+		Headers:     harHeaders(resp.Header),
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+	}
+
+	// Go speaks HTTP/1.1, or HTTP/2 when the server offers it
+	entry.Request.HTTPVersion = "HTTP/1.1"
+	if resp.ProtoMajor == 2 {
+		entry.Request.HTTPVersion = resp.Proto
+	}
+
+	resp.Body = &harBody{ReadCloser: resp.Body, done: func(body []byte, size int) {
+		finished := time.Now()
+
+		entry.Response.BodySize = size
+		entry.Response.Content = harContentOf(body, size, resp.Header.Get("Content-Type"))
+		entry.Time = float64(finished.Sub(started)) / float64(time.Millisecond)
+		entry.Timings = harTimings{
+			Wait:    float64(headers.Sub(started)) / float64(time.Millisecond),
+			Receive: float64(finished.Sub(headers)) / float64(time.Millisecond),
+		}
+
+		t.log.add(entry)
+	}}
+
+	return resp, nil
+}
+
+// harRequestOf records req as it goes on the wire, the body of a request that can be replayed included
+func harRequestOf(req *http.Request) harRequest {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	r := harRequest{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		Cookies:     harCookies(req.Cookies()),
+		Headers:     append([]harNameValue{{"Host", host}}, harHeaders(req.Header)...),
+		QueryString: make([]harNameValue, 0),
+		HeadersSize: -1,
+	}
+
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			r.QueryString = append(r.QueryString, harNameValue{name, value})
+		}
+	}
+
+	if req.GetBody != nil && req.ContentLength != 0 {
+		if body, err := req.GetBody(); err == nil {
+			data, _ := io.ReadAll(io.LimitReader(body, harMaxBody))
+			body.Close()
+
+			r.BodySize = len(data)
+			r.PostData = &harPostData{MimeType: req.Header.Get("Content-Type"), Text: string(data)}
+		}
+	}
+
+	return r
+}
+
+func harHeaders(header http.Header) []harNameValue {
+	list := make([]harNameValue, 0, len(header))
+
+	for name, values := range header {
+		for _, value := range values {
+			list = append(list, harNameValue{name, value})
+		}
+	}
+
+	return list
+}
+
+func harCookies(cookies []*http.Cookie) []harNameValue {
+	list := make([]harNameValue, 0, len(cookies))
+
+	for _, cookie := range cookies {
+		list = append(list, harNameValue{cookie.Name, cookie.Value})
+	}
+
+	return list
+}
+
+// harContentOf records a body as text, or base64 when it isn't UTF-8 (images, compressed bodies)
+func harContentOf(body []byte, size int, mimeType string) harContent {
+	content := harContent{Size: size, MimeType: mimeType}
+
+	if utf8.Valid(body) {
+		content.Text = string(body)
+	} else {
+		content.Text = base64.StdEncoding.EncodeToString(body)
+		content.Encoding = "base64"
+	}
+
+	return content
+}
+
+// harBody keeps the first harMaxBody bytes read, and hands them over once when the body is closed
+type harBody struct {
+	io.ReadCloser
+
+	buf  bytes.Buffer
+	size int
+	once sync.Once
+	done func(body []byte, size int)
+}
+
+func (b *harBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	if keep := min(n, harMaxBody-b.buf.Len()); keep > 0 {
+		b.buf.Write(p[:keep])
+	}
+	b.size += n
+
+	return n, err
+}
+
+func (b *harBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(b.buf.Bytes(), b.size) })
+
+	return err
+}
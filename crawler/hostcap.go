@@ -0,0 +1,47 @@
+package crawler
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/gocolly/colly"
+)
+
+// hostCounter counts the requests made to each host of a crawl
+type hostCounter struct {
+	mu    sync.Mutex
+	pages map[string]int
+}
+
+// allowDepth counts a request to host at the specified depth, unless the host already had
+// threshold requests and depth is past the cap, then it returns false and the request is skipped
+func (hc *hostCounter) allowDepth(host string, depth int, threshold int, capped int) bool {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	if hc.pages == nil {
+		hc.pages = make(map[string]int)
+	}
+
+	if hc.pages[host] >= threshold && depth > capped {
+		return false
+	}
+
+	hc.pages[host]++
+
+	return true
+}
+
+// capHostDepth aborts requests past -host-depth on secondary hosts that exceeded -host-threshold pages,
+// the crawled host itself always gets the full depth
+func (cr *crawl) capHostDepth(r *colly.Request) {
+	host := strings.ToLower(r.URL.Hostname())
+
+	if host == strings.ToLower(cr.hostname) {
+		return
+	}
+
+	if !cr.hostPages.allowDepth(host, r.Depth, cr.cfg.HostThreshold, cr.cfg.HostDepth) {
+		cr.skip(r)
+	}
+}
@@ -0,0 +1,102 @@
+package crawler
+
+import (
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/gocolly/colly"
+)
+
+// hstsHosts holds the hosts that sent a Strict-Transport-Security header during the crawl
+type hstsHosts struct {
+	mu sync.RWMutex
+
+	// host => whether the policy includes subdomains
+	hosts map[string]bool
+}
+
+// observe records (or drops, for max-age=0) the HSTS policy of a response.
+// Like browsers, only policies received over https are honoured
+func (h *hstsHosts) observe(r *colly.Response) {
+	header := r.Headers.Get("Strict-Transport-Security")
+
+	if header == "" || r.Request.URL.Scheme != "https" {
+		return
+	}
+
+	host := strings.ToLower(r.Request.URL.Hostname())
+	includeSubdomains := false
+	expired := false
+
+	for _, directive := range strings.Split(header, ";") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+
+		if directive == "includesubdomains" {
+			includeSubdomains = true
+		} else if strings.HasPrefix(directive, "max-age=") && strings.Trim(directive[len("max-age="):], `"`) == "0" {
+			expired = true
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.hosts == nil {
+		h.hosts = make(map[string]bool)
+	}
+
+	if expired {
+		delete(h.hosts, host)
+	} else {
+		h.hosts[host] = includeSubdomains
+	}
+}
+
+// known reports whether a policy applies to host, set by the host itself or a parent including subdomains
+func (h *hstsHosts) known(host string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	host = strings.ToLower(host)
+
+	if _, ok := h.hosts[host]; ok {
+		return true
+	}
+
+	for i := strings.Index(host, "."); i != -1; i = strings.Index(host, ".") {
+		host = host[i+1:]
+
+		if includeSubdomains, ok := h.hosts[host]; ok && includeSubdomains {
+			return true
+		}
+	}
+
+	return false
+}
+
+// upgrade rewrites an absolute http URL to https when the host is known to use HSTS,
+// port 80 becomes the default https port and other ports are kept
+func (h *hstsHosts) upgrade(link string) string {
+	u, err := url.Parse(link)
+
+	if err != nil || u.Scheme != "http" || !h.known(u.Hostname()) {
+		return link
+	}
+
+	u.Scheme = "https"
+
+	if u.Port() == "80" {
+		u.Host = u.Hostname()
+
+		// keep the brackets of IPv6 literals
+		if strings.Contains(u.Host, ":") {
+			u.Host = "[" + u.Host + "]"
+		}
+	} else if u.Port() != "" {
+		u.Host = net.JoinHostPort(u.Hostname(), u.Port())
+	}
+
+	return u.String()
+}
@@ -0,0 +1,176 @@
+package crawler
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InfraFilter keeps the crawl on hosts whose addresses belong to the allowed ASNs and countries.
+// The database lists IP ranges in the tab-separated format of iptoasn.com:
+// range start, range end, AS number, country code and AS description
+type InfraFilter struct {
+	ranges []ipRange
+
+	// empty sets don't filter, a host must match both when both are set
+	ASNs      map[int]bool
+	Countries map[string]bool
+}
+
+// ipRange is one line of the database
+type ipRange struct {
+	start   netip.Addr
+	end     netip.Addr
+	asn     int
+	country string
+}
+
+// LoadInfraFilter reads the IP ranges of an ASN database
+func LoadInfraFilter(path string) (*InfraFilter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	filter := &InfraFilter{ASNs: make(map[int]bool), Countries: make(map[string]bool)}
+	s := bufio.NewScanner(f)
+	n := 0
+
+	for s.Scan() {
+		n++
+		line := strings.TrimSpace(s.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("line %d: expected start, end, ASN and country", n)
+		}
+
+		start, err1 := netip.ParseAddr(fields[0])
+		end, err2 := netip.ParseAddr(fields[1])
+		asn, err3 := strconv.Atoi(fields[2])
+
+		if err1 != nil || err2 != nil || err3 != nil || start.Is4() != end.Is4() || end.Less(start) {
+			return nil, fmt.Errorf("line %d: invalid range %q", n, line)
+		}
+
+		filter.ranges = append(filter.ranges, ipRange{start: start, end: end, asn: asn, country: strings.ToUpper(fields[3])})
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(filter.ranges, func(i, j int) bool {
+		return filter.ranges[i].start.Less(filter.ranges[j].start)
+	})
+
+	return filter, nil
+}
+
+// lookup returns the range holding ip, ranges don't overlap
+func (f *InfraFilter) lookup(ip netip.Addr) (ipRange, bool) {
+	ip = ip.Unmap()
+
+	// the last range starting at or before ip
+	i := sort.Search(len(f.ranges), func(i int) bool {
+		return ip.Less(f.ranges[i].start)
+	}) - 1
+
+	if i < 0 || f.ranges[i].end.Less(ip) {
+		return ipRange{}, false
+	}
+
+	return f.ranges[i], true
+}
+
+// Allows reports whether every address belongs to an allowed ASN and country,
+// addresses missing from the database are third-party as far as the filter knows
+func (f *InfraFilter) Allows(ips []netip.Addr) bool {
+	if len(ips) == 0 {
+		return false
+	}
+
+	for _, ip := range ips {
+		r, ok := f.lookup(ip)
+
+		if !ok || (len(f.ASNs) > 0 && !f.ASNs[r.asn]) || (len(f.Countries) > 0 && !f.Countries[r.country]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ParseASNs parses a comma-separated list of AS numbers, with or without the AS prefix, e.g. "AS13335,15169"
+func ParseASNs(list string) (map[int]bool, error) {
+	asns := make(map[int]bool)
+
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		asn, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(field), "AS"))
+
+		if err != nil || asn < 1 {
+			return nil, fmt.Errorf("invalid ASN %q", field)
+		}
+
+		asns[asn] = true
+	}
+
+	return asns, nil
+}
+
+// ParseCountries parses a comma-separated list of ISO country codes, e.g. "US,DE"
+func ParseCountries(list string) (map[string]bool, error) {
+	countries := make(map[string]bool)
+
+	for _, field := range strings.Split(list, ",") {
+		code := strings.ToUpper(strings.TrimSpace(field))
+
+		if len(code) != 2 {
+			return nil, fmt.Errorf("invalid country code %q", field)
+		}
+
+		countries[code] = true
+	}
+
+	return countries, nil
+}
+
+// onAllowedInfra resolves host once per crawl and checks its addresses with -asn-db
+func (cr *crawl) onAllowedInfra(host string) bool {
+	if allowed, ok := cr.infraHosts.Load(host); ok {
+		return allowed.(bool)
+	}
+
+	var ips []netip.Addr
+
+	if ip, err := netip.ParseAddr(host); err == nil {
+		ips = []netip.Addr{ip}
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		ips, _ = net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	}
+
+	allowed := cr.cfg.Infra.Allows(ips)
+
+	// report each skipped host once
+	if _, loaded := cr.infraHosts.LoadOrStore(host, allowed); !loaded && !allowed && cr.cfg.Verbose {
+		cr.logf("Skipping %s: its addresses are outside the allowed ASNs and countries\n", host)
+	}
+
+	return allowed
+}
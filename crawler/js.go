@@ -0,0 +1,99 @@
+package crawler
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gocolly/colly"
+)
+
+// quoted strings that look like URLs or paths: "https://x/y", "//cdn/x", "/api/users", "./a", '../b'.
+// Template literals count too, their interpolations are left out below
+var jsURLRe = regexp.MustCompile("[\"'`]((?:https?:)?//[^\"'`\\s<>]+|/[A-Za-z0-9_~.%-][^\"'`\\s<>]*|\\.\\.?/[^\"'`\\s<>]+)[\"'`]")
+
+// quoted relative paths without a leading slash, like LinkFinder finds them: "api/v1/users",
+// "static/js/main.js?v=2", and file names with a web extension ("login.php")
+var jsRelativeURLRe = regexp.MustCompile("[\"'`]([A-Za-z0-9_-]+(?:/[A-Za-z0-9_.~-]+)+/?(?:[?#][^\"'`\\s<>]*)?|[A-Za-z0-9_-]+\\.(?:php|aspx?|jsp|json|action|html?|js|txt|xml)(?:[?#][^\"'`\\s<>]*)?)[\"'`]")
+
+// first segments of the media types scripts are full of ("text/html"), they aren't paths
+var mediaTypes = map[string]bool{
+	"application": true, "audio": true, "font": true, "image": true,
+	"message": true, "multipart": true, "text": true, "video": true,
+}
+
+// jsURLs returns the URL-like string literals of a JavaScript source
+func jsURLs(source string) []string {
+	links := make([]string, 0)
+
+	for _, match := range jsURLRe.FindAllStringSubmatch(source, -1) {
+		// "${base}/users" can't be resolved without running the script
+		if !strings.Contains(match[1], "${") {
+			links = append(links, match[1])
+		}
+	}
+
+	for _, match := range jsRelativeURLRe.FindAllStringSubmatch(source, -1) {
+		if isRelativeEndpoint(match[1]) {
+			links = append(links, match[1])
+		}
+	}
+
+	return links
+}
+
+// isRelativeEndpoint weeds out the relative-looking literals that aren't paths:
+// media types, dates ("10/12/2022") and short fractions like "a/b"
+func isRelativeEndpoint(link string) bool {
+	path, _, _ := strings.Cut(link, "?")
+	path, _, _ = strings.Cut(path, "#")
+	segments := strings.Split(strings.TrimSuffix(path, "/"), "/")
+
+	if len(segments) == 2 && mediaTypes[strings.ToLower(segments[0])] {
+		return false
+	}
+
+	// file names matched on their extension alone
+	if len(segments) == 1 {
+		return true
+	}
+
+	long := false
+
+	for _, segment := range segments {
+		if strings.Trim(segment, "0123456789") == "" {
+			continue
+		}
+
+		// a word of 3 characters or more, with a letter
+		if len(segment) >= 3 {
+			long = true
+		}
+	}
+
+	return long
+}
+
+// isJavaScript reports whether a response is a script, by its Content-Type or the extension of its URL
+func isJavaScript(r *colly.Response) bool {
+	contentType := strings.ToLower(r.Headers.Get("Content-Type"))
+
+	if strings.Contains(contentType, "javascript") || strings.Contains(contentType, "ecmascript") {
+		return true
+	}
+
+	path := strings.ToLower(r.Request.URL.Path)
+
+	return strings.HasSuffix(path, ".js") || strings.HasSuffix(path, ".mjs")
+}
+
+// extractJSURLs records the URLs found in a script fetched (or inlined) by r,
+// relative ones are resolved against the URL of r
+func (cr *crawl) extractJSURLs(r *colly.Request, source string) {
+	for _, link := range jsURLs(source) {
+		if !cr.allowLink(r) {
+			return
+		}
+
+		cr.appendResult(link, "js", r)
+	}
+}
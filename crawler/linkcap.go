@@ -0,0 +1,33 @@
+package crawler
+
+import (
+	"sync/atomic"
+
+	"github.com/gocolly/colly"
+)
+
+// DefaultMaxLinks is the default of -max-links, far above what real pages link to
+const DefaultMaxLinks = 10000
+
+// allowLink counts a link extracted from the page requested by r and reports whether it's
+// within the -max-links cap, generated pages with millions of anchors would exhaust memory otherwise
+func (cr *crawl) allowLink(r *colly.Request) bool {
+	if cr.cfg.MaxLinks <= 0 {
+		return true
+	}
+
+	counter, _ := cr.pageLinks.LoadOrStore(r.ID, new(int64))
+	n := atomic.AddInt64(counter.(*int64), 1)
+
+	// warn once per page
+	if n == int64(cr.cfg.MaxLinks)+1 && cr.cfg.Verbose {
+		cr.logf("Ignoring the links of %s beyond the first %d\n", r.URL, cr.cfg.MaxLinks)
+	}
+
+	return n <= int64(cr.cfg.MaxLinks)
+}
+
+// forgetLinks drops the link counter of a page once it's scraped
+func (cr *crawl) forgetLinks(r *colly.Request) {
+	cr.pageLinks.Delete(r.ID)
+}
@@ -0,0 +1,60 @@
+package crawler
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/gocolly/colly"
+)
+
+func TestAllowLink(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxLinks int
+		links    int
+		want     int
+	}{
+		{"no cap", 0, 50, 50},
+		{"under the cap", 10, 5, 5},
+		{"at the cap", 5, 5, 5},
+		{"over the cap", 3, 10, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := &crawl{cfg: &Config{MaxLinks: tt.maxLinks}}
+			page := &colly.Request{ID: 1, URL: &url.URL{Scheme: "https", Host: "x.com", Path: "/"}}
+
+			allowed := 0
+			for i := 0; i < tt.links; i++ {
+				if cr.allowLink(page) {
+					allowed++
+				}
+			}
+
+			if allowed != tt.want {
+				t.Errorf("%d of %d links allowed, want %d", allowed, tt.links, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowLinkPerPage(t *testing.T) {
+	cr := &crawl{cfg: &Config{MaxLinks: 1}}
+	first := &colly.Request{ID: 1, URL: &url.URL{Scheme: "https", Host: "x.com", Path: "/a"}}
+	second := &colly.Request{ID: 2, URL: &url.URL{Scheme: "https", Host: "x.com", Path: "/b"}}
+
+	if !cr.allowLink(first) || cr.allowLink(first) {
+		t.Fatal("the first page isn't capped at one link")
+	}
+
+	if !cr.allowLink(second) {
+		t.Error("the cap of a page counts the links of another page")
+	}
+
+	// a page scraped again after forgetLinks starts over
+	cr.forgetLinks(first)
+	if !cr.allowLink(first) {
+		t.Error("forgetLinks kept the count of the page")
+	}
+}
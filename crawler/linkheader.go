@@ -0,0 +1,96 @@
+package crawler
+
+import (
+	"strings"
+
+	"github.com/gocolly/colly"
+)
+
+// headerLink is one entry of a Link response header
+type headerLink struct {
+	target string
+	rels   []string
+}
+
+// parseLinkHeader parses a Link header value like `<https://x/?page=2>; rel="next", </style.css>; rel=preload`.
+// Commas and semicolons inside <...> or quotes don't split entries
+func parseLinkHeader(value string) []headerLink {
+	links := make([]headerLink, 0)
+
+	for _, entry := range splitOutside(value, ',') {
+		parts := splitOutside(entry, ';')
+		target := strings.TrimSpace(parts[0])
+
+		if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+
+		link := headerLink{target: strings.TrimSpace(target[1 : len(target)-1])}
+
+		for _, param := range parts[1:] {
+			name, val, _ := strings.Cut(param, "=")
+
+			if strings.EqualFold(strings.TrimSpace(name), "rel") {
+				link.rels = strings.Fields(strings.ToLower(strings.Trim(strings.TrimSpace(val), `"`)))
+			}
+		}
+
+		links = append(links, link)
+	}
+
+	return links
+}
+
+// splitOutside splits s on sep, ignoring separators between <> or double quotes
+func splitOutside(s string, sep rune) []string {
+	parts := make([]string, 0)
+	inURI, inQuote := false, false
+	start := 0
+
+	for i, ch := range s {
+		switch {
+		case ch == '"' && !inURI:
+			inQuote = !inQuote
+		case ch == '<' && !inQuote:
+			inURI = true
+		case ch == '>' && !inQuote:
+			inURI = false
+		case ch == sep && !inURI && !inQuote:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+// hasRel reports whether the link has one of the relation types
+func (l headerLink) hasRel(rels ...string) bool {
+	for _, have := range l.rels {
+		for _, want := range rels {
+			if have == want {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// extractLinkHeaders records the targets of the Link headers of a response
+// and follows pagination (rel=next/prev) so paginated APIs get crawled entirely
+func (cr *crawl) extractLinkHeaders(r *colly.Response) {
+	for _, value := range (*r.Headers)["Link"] {
+		for _, link := range parseLinkHeader(value) {
+			if !cr.allowLink(r.Request) {
+				return
+			}
+
+			cr.appendResult(link.target, "link-header", r.Request)
+
+			if link.hasRel("next", "prev", "previous") {
+				cr.follow(r.Request, link.target)
+			}
+		}
+	}
+}
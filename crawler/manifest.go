@@ -0,0 +1,142 @@
+package crawler
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/gocolly/colly"
+)
+
+// well-known paths of web app manifests and service workers, probed on the starting origin with -manifests
+var (
+	wellKnownManifests      = []string{"/manifest.json", "/manifest.webmanifest", "/asset-manifest.json"}
+	wellKnownServiceWorkers = []string{"/sw.js", "/service-worker.js"}
+)
+
+// navigator.serviceWorker.register("/sw.js")
+var serviceWorkerRe = regexp.MustCompile("serviceWorker\\s*\\.\\s*register\\(\\s*[\"'`]([^\"'`\\s]+)[\"'`]")
+
+// manifestURLs returns the string values of a JSON manifest that look like URLs or paths,
+// at any depth: start_url, icons[].src, shortcuts[].url, the files of an asset-manifest.json...
+func manifestURLs(body []byte) ([]string, error) {
+	var doc interface{}
+
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, err
+	}
+
+	links := make([]string, 0)
+
+	var walk func(key string, value interface{})
+	walk = func(key string, value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for k, child := range v {
+				walk(k, child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(key, child)
+			}
+		case string:
+			// "type" holds MIME types like image/png
+			if key != "type" && isManifestPath(v) {
+				links = append(links, v)
+			}
+		}
+	}
+
+	walk("", doc)
+
+	return links, nil
+}
+
+// isManifestPath reports whether a manifest value is a URL, an absolute path or a relative one like static/js/main.js
+func isManifestPath(value string) bool {
+	if value == "" || strings.ContainsAny(value, " \t\n<>\"'") {
+		return false
+	}
+
+	if strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") {
+		return true
+	}
+
+	// other schemes (data:, mailto:) aren't crawlable
+	return !strings.Contains(value, ":") && (strings.HasPrefix(value, "/") || strings.HasPrefix(value, ".") || strings.Contains(value, "/"))
+}
+
+// followManifest marks link as a manifest (or a service worker, with worker) and follows it, so its URLs get extracted
+func (cr *crawl) followManifest(r *colly.Request, link string, worker bool) {
+	if absolute := lowerHost(r.AbsoluteURL(link)); absolute != "" {
+		cr.manifests.Store(absolute, worker)
+		cr.follow(r, link)
+	}
+}
+
+// manifestSeeds returns the well-known manifest and service worker URLs of the origin of link, marked as such
+func (cr *crawl) manifestSeeds(link string) []string {
+	u, err := url.Parse(link)
+
+	if err != nil {
+		return nil
+	}
+
+	seeds := make([]string, 0)
+
+	for _, paths := range []struct {
+		list   []string
+		worker bool
+	}{{wellKnownManifests, false}, {wellKnownServiceWorkers, true}} {
+		for _, path := range paths.list {
+			seed := u.Scheme + "://" + u.Host + path
+			cr.manifests.Store(seed, paths.worker)
+			seeds = append(seeds, seed)
+		}
+	}
+
+	return seeds
+}
+
+// extractManifest records and follows the URLs of a manifest or service worker fetched by r,
+// they are resolved against the URL of the file
+func (cr *crawl) extractManifest(r *colly.Response) {
+	worker, ok := cr.manifests.Load(r.Request.URL.String())
+
+	if !ok {
+		return
+	}
+
+	var links []string
+	kind := "manifest"
+
+	if worker.(bool) {
+		// precache lists are string literals
+		links, kind = jsURLs(string(r.Body)), "service-worker"
+	} else {
+		var err error
+		if links, err = manifestURLs(r.Body); err != nil {
+			return
+		}
+	}
+
+	for _, link := range links {
+		if !cr.allowLink(r.Request) {
+			return
+		}
+
+		cr.appendResult(link, kind, r.Request)
+		cr.follow(r.Request, link)
+	}
+}
+
+// extractServiceWorkers follows the service workers a script registers
+func (cr *crawl) extractServiceWorkers(r *colly.Request, source string) {
+	for _, match := range serviceWorkerRe.FindAllStringSubmatch(source, -1) {
+		if cr.allowLink(r) {
+			cr.appendResult(match[1], "service-worker", r)
+			cr.followManifest(r, match[1], true)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package crawler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gocolly/colly"
+)
+
+// Metrics counts the work of the crawls sharing it as it happens, for the progress lines of -stats
+// and the Prometheus endpoint of -metrics. It's safe for concurrent use
+type Metrics struct {
+	started time.Time
+
+	// requests that got a response or failed, and the failed ones
+	requests int64
+	errors   int64
+
+	// requests on the wire waiting for their response, and links waiting in the frontiers of -order and -max-goroutines
+	inFlight int64
+	queued   int64
+
+	// unique URLs recorded, and the crawls running and done
+	urls    int64
+	running int64
+	done    int64
+
+	mu         sync.Mutex
+	statuses   map[int]int64
+	errorTypes map[string]int64
+}
+
+// MetricsSnapshot is the state of Metrics at a point in time
+type MetricsSnapshot struct {
+	Elapsed time.Duration
+
+	Requests int64
+	Errors   int64
+	InFlight int64
+	Queued   int64
+	URLs     int64
+	Running  int64
+	Done     int64
+
+	// status => responses, error type (timeout, dns, refused, reset, tls, denied, status, other) => failed requests
+	Statuses   map[int]int64
+	ErrorTypes map[string]int64
+}
+
+// NewMetrics returns metrics starting now
+func NewMetrics() *Metrics {
+	return &Metrics{started: time.Now(), statuses: make(map[int]int64), errorTypes: make(map[string]int64)}
+}
+
+// Snapshot returns the current counts
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	s := MetricsSnapshot{
+		Elapsed:    time.Since(m.started),
+		Requests:   atomic.LoadInt64(&m.requests),
+		Errors:     atomic.LoadInt64(&m.errors),
+		InFlight:   atomic.LoadInt64(&m.inFlight),
+		Queued:     atomic.LoadInt64(&m.queued),
+		URLs:       atomic.LoadInt64(&m.urls),
+		Running:    atomic.LoadInt64(&m.running),
+		Done:       atomic.LoadInt64(&m.done),
+		Statuses:   make(map[int]int64),
+		ErrorTypes: make(map[string]int64),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for status, count := range m.statuses {
+		s.Statuses[status] = count
+	}
+
+	for kind, count := range m.errorTypes {
+		s.ErrorTypes[kind] = count
+	}
+
+	return s
+}
+
+// WritePrometheus writes the metrics in the Prometheus text format
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	s := m.Snapshot()
+
+	metric := func(name string, kind string, help string, value interface{}) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+	}
+
+	metric("rockrawler_requests_total", "counter", "Requests that got a response or failed.", s.Requests)
+	metric("rockrawler_errors_total", "counter", "Requests that failed.", s.Errors)
+	metric("rockrawler_requests_in_flight", "gauge", "Requests on the wire waiting for their response.", s.InFlight)
+	metric("rockrawler_queued_links", "gauge", "Links waiting in the frontiers of -order and -max-goroutines.", s.Queued)
+	metric("rockrawler_urls_total", "counter", "Unique URLs recorded.", s.URLs)
+	metric("rockrawler_crawls_running", "gauge", "Targets being crawled.", s.Running)
+	metric("rockrawler_crawls_done_total", "counter", "Targets crawled.", s.Done)
+	metric("rockrawler_uptime_seconds", "gauge", "Seconds since the run started.", strconv.FormatFloat(s.Elapsed.Seconds(), 'f', 3, 64))
+
+	fmt.Fprint(w, "# HELP rockrawler_responses_total Responses by status.\n# TYPE rockrawler_responses_total counter\n")
+	for _, status := range sortedStatuses(s.Statuses) {
+		fmt.Fprintf(w, "rockrawler_responses_total{status=\"%d\"} %d\n", status, s.Statuses[status])
+	}
+
+	fmt.Fprint(w, "# HELP rockrawler_request_errors_total Failed requests by error type.\n# TYPE rockrawler_request_errors_total counter\n")
+	for _, kind := range sortedKeys(s.ErrorTypes) {
+		fmt.Fprintf(w, "rockrawler_request_errors_total{type=%q} %d\n", kind, s.ErrorTypes[kind])
+	}
+
+	return nil
+}
+
+// watch counts the requests of the collector, aborted requests aren't made
+func (m *Metrics) watch(c *colly.Collector) {
+	c.OnResponse(func(r *colly.Response) {
+		atomic.AddInt64(&m.requests, 1)
+		m.count(r.StatusCode, "")
+	})
+
+	c.OnError(func(r *colly.Response, err error) {
+		atomic.AddInt64(&m.requests, 1)
+		atomic.AddInt64(&m.errors, 1)
+
+		kind := errorType(err)
+		if r.StatusCode != 0 {
+			kind = "status"
+		}
+
+		m.count(r.StatusCode, kind)
+	})
+}
+
+// count adds a response of status (0 without one) and an error of kind (empty without one)
+func (m *Metrics) count(status int, kind string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if status != 0 {
+		m.statuses[status]++
+	}
+
+	if kind != "" {
+		m.errorTypes[kind]++
+	}
+}
+
+// errorType sorts the error of a request that got no response
+func errorType(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var recordErr tls.RecordHeaderError
+
+	switch {
+	case errors.Is(err, errDenied):
+		return "denied"
+	case errors.As(err, &dnsErr):
+		return "dns"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "refused"
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "reset"
+	case errors.As(err, &certErr), errors.As(err, &unknownAuthority), errors.As(err, &hostnameErr), errors.As(err, &recordErr):
+		return "tls"
+	}
+
+	return "other"
+}
+
+func sortedStatuses(statuses map[int]int64) []int {
+	list := make([]int, 0, len(statuses))
+	for status := range statuses {
+		list = append(list, status)
+	}
+	sort.Ints(list)
+
+	return list
+}
+
+func sortedKeys(counts map[string]int64) []string {
+	list := make([]string, 0, len(counts))
+	for key := range counts {
+		list = append(list, key)
+	}
+	sort.Strings(list)
+
+	return list
+}
+
+// metricsTransport counts the requests waiting for their response, retries and redirects one by one
+type metricsTransport struct {
+	next    http.RoundTripper
+	metrics *Metrics
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt64(&t.metrics.inFlight, 1)
+	defer atomic.AddInt64(&t.metrics.inFlight, -1)
+
+	return t.next.RoundTrip(req)
+}
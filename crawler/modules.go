@@ -0,0 +1,70 @@
+package crawler
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gocolly/colly"
+)
+
+var (
+	// static imports and re-exports: import x from "./a.js", import "./a.js", export * from "./a.js"
+	staticImportRe = regexp.MustCompile(`(?:^|[;\s])(?:import|export)\s*(?:[\w*{}\s,$]+?\s*from\s*)?["']([^"'\s]+)["']`)
+
+	// dynamic imports of a plain string literal: import("./a.js")
+	dynamicImportRe = regexp.MustCompile(`\bimport\(\s*["']([^"'\s]+)["']\s*\)`)
+)
+
+// importSpecifiers returns the import specifiers of a JavaScript module that point to URLs.
+// Bare specifiers (import "react") are resolved by bundlers or import maps, so they are left out
+func importSpecifiers(source string) []string {
+	specifiers := make([]string, 0)
+
+	for _, re := range []*regexp.Regexp{staticImportRe, dynamicImportRe} {
+		for _, match := range re.FindAllStringSubmatch(source, -1) {
+			if isURLSpecifier(match[1]) {
+				specifiers = append(specifiers, match[1])
+			}
+		}
+	}
+
+	return specifiers
+}
+
+// isURLSpecifier reports whether an import specifier is a relative or absolute URL
+func isURLSpecifier(specifier string) bool {
+	for _, prefix := range []string{"./", "../", "/", "http://", "https://"} {
+		if strings.HasPrefix(specifier, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// followModule marks link as a JavaScript module and follows it, so its own imports get extracted
+func (cr *crawl) followModule(r *colly.Request, link string) {
+	if absolute := lowerHost(r.AbsoluteURL(link)); absolute != "" {
+		cr.modules.Store(absolute, true)
+		cr.follow(r, link)
+	}
+}
+
+// isModule reports whether the URL was followed as a JavaScript module
+func (cr *crawl) isModule(link string) bool {
+	_, ok := cr.modules.Load(link)
+	return ok
+}
+
+// extractImports records and follows the imports of a module fetched (or inlined) by r,
+// specifiers are resolved relative to the URL of r
+func (cr *crawl) extractImports(r *colly.Request, source string) {
+	for _, specifier := range importSpecifiers(source) {
+		if !cr.allowLink(r) {
+			return
+		}
+
+		cr.appendResult(specifier, "module", r)
+		cr.followModule(r, specifier)
+	}
+}
@@ -0,0 +1,86 @@
+package crawler
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// normalizeURL rewrites trivially different spellings of a URL to a single form:
+// the scheme and host are lowercased, default ports (80 for http, 443 for https) and the
+// fragment are removed, and query parameters are sorted by key (keeping the order of repeated keys).
+// URLs that can't be parsed are returned unchanged
+func normalizeURL(link string) string {
+	u, err := url.Parse(link)
+
+	if err != nil {
+		return link
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	if (u.Scheme == "http" && u.Port() == "80") || (u.Scheme == "https" && u.Port() == "443") {
+		u.Host = strings.TrimSuffix(u.Host, ":"+u.Port())
+	}
+
+	u.RawQuery = sortQuery(u.RawQuery)
+	u.ForceQuery = false
+
+	return u.String()
+}
+
+// trimTrailingSlash removes the trailing slash of a path, "/a/" and "/a" name the same page on most servers.
+// The root path is kept
+func trimTrailingSlash(link string) string {
+	u, err := url.Parse(link)
+
+	if err != nil || len(u.Path) <= 1 || !strings.HasSuffix(u.Path, "/") {
+		return link
+	}
+
+	u.Path = strings.TrimRight(u.Path, "/")
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.RawPath = ""
+
+	return u.String()
+}
+
+// lowerHost lowercases the host of a URL and leaves the rest as-is.
+// Hostnames are case-insensitive, but colly compares them exactly when scoping requests
+func lowerHost(link string) string {
+	u, err := url.Parse(link)
+
+	if err != nil || u.Host == strings.ToLower(u.Host) {
+		return link
+	}
+
+	u.Host = strings.ToLower(u.Host)
+
+	return u.String()
+}
+
+// sortQuery sorts the parameters of a raw query string by key without re-encoding them
+func sortQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	params := strings.Split(rawQuery, "&")
+
+	sort.SliceStable(params, func(i, j int) bool {
+		return queryKey(params[i]) < queryKey(params[j])
+	})
+
+	return strings.Join(params, "&")
+}
+
+// queryKey returns the key of a key=value query parameter
+func queryKey(param string) string {
+	key, _, _ := strings.Cut(param, "=")
+	return key
+}
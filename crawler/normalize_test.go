@@ -0,0 +1,75 @@
+package crawler
+
+import "testing"
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+		want string
+	}{
+		{"sorted query", "https://x.com/a?c=2&b=1", "https://x.com/a?b=1&c=2"},
+		{"already sorted", "https://x.com/a?b=1&c=2", "https://x.com/a?b=1&c=2"},
+		{"repeated keys keep their order", "https://x.com/a?b=2&a=1&b=1", "https://x.com/a?a=1&b=2&b=1"},
+		{"lowercased scheme and host", "HTTPS://X.Example.COM/Path", "https://x.example.com/Path"},
+		{"default http port", "http://x.com:80/a", "http://x.com/a"},
+		{"default https port", "https://x.com:443/a", "https://x.com/a"},
+		{"other port kept", "https://x.com:8443/a", "https://x.com:8443/a"},
+		{"http port on https kept", "https://x.com:80/a", "https://x.com:80/a"},
+		{"fragment removed", "https://x.com/a#top", "https://x.com/a"},
+		{"empty query removed", "https://x.com/a?", "https://x.com/a"},
+		{"encoding kept", "https://x.com/a%20b?q=a%2Bb", "https://x.com/a%20b?q=a%2Bb"},
+		{"trailing slash kept", "https://x.com/a/", "https://x.com/a/"},
+		{"unparsable unchanged", "https://x.com/%zz", "https://x.com/%zz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeURL(tt.link); got != tt.want {
+				t.Errorf("normalizeURL(%q) = %q, want %q", tt.link, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimTrailingSlash(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+		want string
+	}{
+		{"trailing slash", "https://x.com/a/", "https://x.com/a"},
+		{"several slashes", "https://x.com/a//", "https://x.com/a"},
+		{"no slash", "https://x.com/a", "https://x.com/a"},
+		{"root kept", "https://x.com/", "https://x.com/"},
+		{"no path", "https://x.com", "https://x.com"},
+		{"query kept", "https://x.com/a/?b=1", "https://x.com/a?b=1"},
+		{"slash only path collapses to root", "https://x.com//", "https://x.com/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trimTrailingSlash(tt.link); got != tt.want {
+				t.Errorf("trimTrailingSlash(%q) = %q, want %q", tt.link, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLowerHost(t *testing.T) {
+	tests := []struct {
+		link string
+		want string
+	}{
+		{"https://WWW.Example.COM/Path/App.JS?Q=A", "https://www.example.com/Path/App.JS?Q=A"},
+		{"https://Example.com:8443/", "https://example.com:8443/"},
+		{"https://example.com/a%2Fb", "https://example.com/a%2Fb"},
+		{"/relative/Path", "/relative/Path"},
+	}
+
+	for _, tt := range tests {
+		if got := lowerHost(tt.link); got != tt.want {
+			t.Errorf("lowerHost(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
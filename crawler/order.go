@@ -0,0 +1,138 @@
+package crawler
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/gocolly/colly"
+)
+
+// frontier holds the links waiting to be visited when crawling with -order bfs or dfs.
+// bfs takes the oldest link first (level by level), dfs takes the newest one first
+type frontier struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	lifo   bool
+	items  []frontierItem
+	active int
+
+	// counts the links waiting too when set, for Metrics
+	queued *int64
+}
+
+// frontierItem is a link together with the request it was found on,
+// visiting through the parent keeps colly's depth accounting intact
+type frontierItem struct {
+	parent *colly.Request
+	link   string
+
+	// depth of the request of a link -resume brings back, 0 for the others
+	depth int
+
+	// the link in the progress of -state, empty without
+	key string
+}
+
+// requestDepth is the depth colly requests the link at
+func (item frontierItem) requestDepth() int {
+	if item.depth > 0 {
+		return item.depth
+	} else if item.parent != nil {
+		return item.parent.Depth + 1
+	}
+
+	return 1
+}
+
+func newFrontier(order string) *frontier {
+	f := &frontier{lifo: order == "dfs"}
+	f.cond = sync.NewCond(&f.mu)
+
+	return f
+}
+
+// push queues a link, found on the parent of item or a starting URL when it has none
+func (f *frontier) push(item frontierItem) {
+	f.mu.Lock()
+	f.items = append(f.items, item)
+	f.mu.Unlock()
+
+	if f.queued != nil {
+		atomic.AddInt64(f.queued, 1)
+	}
+
+	f.cond.Signal()
+}
+
+// pop waits for the next link, it returns false once nothing is queued
+// and no worker is busy anymore (so nothing can be queued later)
+func (f *frontier) pop() (frontierItem, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for len(f.items) == 0 {
+		if f.active == 0 {
+			return frontierItem{}, false
+		}
+		f.cond.Wait()
+	}
+
+	var item frontierItem
+	last := len(f.items) - 1
+
+	if f.lifo {
+		item, f.items = f.items[last], f.items[:last]
+	} else {
+		item, f.items = f.items[0], f.items[1:]
+	}
+
+	f.active++
+
+	if f.queued != nil {
+		atomic.AddInt64(f.queued, -1)
+	}
+
+	return item, true
+}
+
+// done marks the item returned by the last pop of a worker as visited
+func (f *frontier) done() {
+	f.mu.Lock()
+	f.active--
+	idle := f.active == 0 && len(f.items) == 0
+	f.mu.Unlock()
+
+	// wake up the waiting workers so they can quit
+	if idle {
+		f.cond.Broadcast()
+	}
+}
+
+// run hands the queued links to visit with the specified number of workers until the frontier drains
+func (f *frontier) run(threads int, visit func(item frontierItem)) {
+	var wg sync.WaitGroup
+
+	if threads < 1 {
+		threads = 1
+	}
+
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				item, ok := f.pop()
+				if !ok {
+					return
+				}
+
+				visit(item)
+				f.done()
+			}
+		}()
+	}
+
+	wg.Wait()
+}
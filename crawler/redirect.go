@@ -0,0 +1,109 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxRedirects is the number of redirects followed by default, like net/http and colly do
+const maxRedirects = 10
+
+// ParseRedirectPolicy validates a -follow-redirects policy: all, scope or none
+func ParseRedirectPolicy(policy string) (string, error) {
+	switch policy = strings.ToLower(strings.TrimSpace(policy)); policy {
+	case "all", "scope", "none":
+		return policy, nil
+	}
+
+	return "", fmt.Errorf("unknown redirect policy %q, use all, scope or none", policy)
+}
+
+// redirect decides whether the collector follows a redirect, per -max-redirects and -follow-redirects.
+// The followed ones get the headers of the previous request like colly does by default
+func (cr *crawl) redirect(req *http.Request, via []*http.Request) error {
+	last := via[len(via)-1]
+
+	if err := cr.checkRedirect(req, via); err != nil {
+		return err
+	}
+
+	for name, values := range last.Header {
+		for _, value := range values {
+			req.Header.Set(name, value)
+		}
+	}
+
+	// credentials stay with their host
+	if req.URL.Host != last.URL.Host {
+		req.Header.Del("Authorization")
+	}
+
+	if cr.tracker != nil {
+		cr.tracker.hop(req, via)
+	}
+
+	return nil
+}
+
+// checkRedirect refuses the redirects the policy doesn't follow, the response redirecting is kept then.
+// It's the redirect policy of the crawl's own requests too, -verify ones included
+func (cr *crawl) checkRedirect(req *http.Request, via []*http.Request) error {
+	limit := cr.cfg.MaxRedirects
+	if limit <= 0 {
+		limit = maxRedirects
+	}
+
+	// redirectTransport records the refusals of -follow-redirects
+	if cr.refusesRedirect(req.URL.String()) {
+		return http.ErrUseLastResponse
+	}
+
+	if len(via) >= limit {
+		cr.addResult(Result{URL: req.URL.String(), Source: via[len(via)-1].URL.String(), Type: "redirect"})
+		return http.ErrUseLastResponse
+	}
+
+	return nil
+}
+
+// refusesRedirect reports whether the policy doesn't follow a redirect to link
+func (cr *crawl) refusesRedirect(link string) bool {
+	switch cr.cfg.FollowRedirects {
+	case "none":
+		return true
+	case "scope":
+		return !cr.inScope(link, 0)
+	}
+
+	return false
+}
+
+// redirectTransport records the targets of the redirects -follow-redirects refuses with type redirect,
+// open redirect candidates among them. It sees the off-scope redirects colly refuses before asking the crawl
+type redirectTransport struct {
+	next http.RoundTripper
+	cr   *crawl
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 {
+		return resp, nil
+	}
+
+	location, err := resp.Location()
+	if err != nil {
+		return resp, nil
+	}
+
+	if link := lowerHost(location.String()); t.cr.refusesRedirect(link) {
+		t.cr.addResult(Result{URL: link, Source: req.URL.String(), Type: "redirect"})
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,363 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/gocolly/colly"
+)
+
+// renderQuiet is how long a page must make no request to be considered settled
+const renderQuiet = 500 * time.Millisecond
+
+// renderer loads the pages of a crawl in a headless Chrome for -render, the browser is started on the first page
+// and has a tab per page being rendered, at most one per thread. The tabs make their requests through the
+// crawl's transport, so the page is the response colly got and the rest follow the rules of the crawl
+type renderer struct {
+	cr     *crawl
+	client *http.Client
+
+	once    sync.Once
+	browser context.Context
+	cancel  context.CancelFunc
+	err     error
+
+	tabs    chan struct{}
+	timeout time.Duration
+}
+
+// renderTimeout bounds a render, a page loading everything it needs takes longer than a request
+func renderTimeout(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	return 3 * timeout
+}
+
+func newRenderer(cr *crawl, timeout time.Duration) *renderer {
+	return &renderer{cr: cr, tabs: make(chan struct{}, max(cr.cfg.Threads, 1)), timeout: timeout}
+
+}
+
+// start launches the browser, once. It's killed when the crawl is cancelled
+func (rd *renderer) start() error {
+	rd.once.Do(func() {
+		opts := append(chromedp.DefaultExecAllocatorOptions[:], chromedp.UserAgent(resolveUserAgent(rd.cr.cfg.UserAgent)))
+
+		if rd.cr.cfg.Insecure {
+			opts = append(opts, chromedp.Flag("ignore-certificate-errors", true))
+		}
+
+		// the requests go through the crawl's transport, the proxy is for those Fetch doesn't see like websockets.
+		// Chrome takes a single proxy without credentials
+		if rd.cr.cfg.Proxy != "" {
+			if proxies, err := ParseProxies(rd.cr.cfg.Proxy); err == nil && len(proxies) > 0 {
+				opts = append(opts, chromedp.ProxyServer(proxies[0].Scheme+"://"+proxies[0].Host))
+			}
+		}
+
+		alloc, cancelAlloc := chromedp.NewExecAllocator(rd.cr.ctx, opts...)
+		browser, cancelBrowser := chromedp.NewContext(alloc)
+
+		rd.browser = browser
+		rd.cancel = func() {
+			cancelBrowser()
+			cancelAlloc()
+		}
+
+		rd.client = tabClient(rd.cr.client.Transport)
+
+		if rd.err = chromedp.Run(browser); rd.err != nil {
+			rd.cr.logf("Rendering disabled, headless Chrome didn't start: %v\n", rd.err)
+		}
+	})
+
+	return rd.err
+}
+
+// tabClient makes the requests of the tabs with the crawl's transport, without its jar and redirect policy:
+// the browser keeps its own cookies and follows the redirects itself. It's not the collector's, -delay doesn't
+// hold up the assets of a page
+func tabClient(transport http.RoundTripper) *http.Client {
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// close kills the browser if it was started
+func (rd *renderer) close() {
+	if rd.cancel != nil {
+		rd.cancel()
+	}
+}
+
+// render loads the page of r with the headers and cookies of the crawl and waits for it to settle.
+// It returns the HTML of the DOM and the URLs the page requested with XMLHttpRequest and fetch
+func (rd *renderer) render(r *colly.Response) (string, []string, error) {
+	link := r.Request.URL.String()
+
+	// the URL Chrome requests for the page, without the fragment and with a path
+	u := *r.Request.URL
+	u.Fragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	pageURL := u.String()
+
+	if err := rd.start(); err != nil {
+		return "", nil, err
+	}
+
+	rd.tabs <- struct{}{}
+	defer func() { <-rd.tabs }()
+
+	tab, cancel := chromedp.NewContext(rd.browser)
+	defer cancel()
+
+	tab, cancelTimeout := context.WithTimeout(tab, rd.timeout)
+	defer cancelTimeout()
+
+	var (
+		mu       sync.Mutex
+		inflight = make(map[network.RequestID]bool)
+		active   = time.Now()
+		xhrs     []string
+		page     bool
+	)
+
+	chromedp.ListenTarget(tab, func(ev interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch ev := ev.(type) {
+		case *fetch.EventRequestPaused:
+			// the page itself was already fetched, the events can't wait for the others
+			first := !page && ev.ResourceType == network.ResourceTypeDocument && ev.Request.URL == pageURL
+			page = page || first
+
+			go rd.intercept(tab, ev, r, first)
+			return
+		case *network.EventRequestWillBeSent:
+			inflight[ev.RequestID] = true
+
+			if ev.Type == network.ResourceTypeXHR || ev.Type == network.ResourceTypeFetch {
+				xhrs = append(xhrs, ev.Request.URL)
+			}
+		case *network.EventLoadingFinished:
+			delete(inflight, ev.RequestID)
+		case *network.EventLoadingFailed:
+			delete(inflight, ev.RequestID)
+		default:
+			return
+		}
+
+		active = time.Now()
+	})
+
+	actions := []chromedp.Action{network.Enable(), fetch.Enable()}
+
+	if rd.cr.cfg.RandomAgent {
+		actions = append(actions, emulation.SetUserAgentOverride(rd.cr.userAgent()))
+	}
+
+	if len(rd.cr.headers) > 0 {
+		headers := make(network.Headers)
+		for header, value := range rd.cr.headers {
+			headers[header] = value
+		}
+		actions = append(actions, network.SetExtraHTTPHeaders(headers))
+	}
+
+	if cookies := rd.cookies(link); len(cookies) > 0 {
+		actions = append(actions, network.SetCookies(cookies))
+	}
+
+	actions = append(actions, chromedp.Navigate(link))
+
+	if err := chromedp.Run(tab, actions...); err != nil {
+		return "", nil, err
+	}
+
+	// scripts keep loading data after the load event, wait for a quiet moment.
+	// Pages polling forever are taken as they are halfway to the timeout
+	settle(tab, rd.timeout/2, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(inflight) == 0 && time.Since(active) >= renderQuiet
+	})
+
+	var html string
+
+	if err := chromedp.Run(tab, chromedp.OuterHTML("html", &html, chromedp.ByQuery)); err != nil {
+		return "", nil, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	return html, xhrs, nil
+}
+
+// settle waits until quiet reports true, for at most wait or until ctx is done
+func settle(ctx context.Context, wait time.Duration, quiet func() bool) {
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for !quiet() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// intercept answers a paused request of a tab: the page with the response of r, the requests the crawl
+// refuses (-deny, -safe, robots.txt, -asn-db, -max-requests) with an error and the others with the response
+// of the crawl's transport
+func (rd *renderer) intercept(tab context.Context, ev *fetch.EventRequestPaused, r *colly.Response, page bool) {
+	ctx := cdp.WithExecutor(tab, chromedp.FromContext(tab).Target)
+
+	var action chromedp.Action
+
+	switch link := ev.Request.URL; {
+	case page:
+		action = fulfill(ev.RequestID, r.StatusCode, *r.Headers, r.Body, !rd.cr.cfg.RawBody)
+	case !strings.HasPrefix(link, "http://") && !strings.HasPrefix(link, "https://"):
+		action = fetch.ContinueRequest(ev.RequestID)
+	case rd.refused(link):
+		action = fetch.FailRequest(ev.RequestID, network.ErrorReasonBlockedByClient)
+	default:
+		action = rd.forward(ev)
+	}
+
+	// the tab closes when the page is done, the requests still paused don't matter then
+	_ = action.Do(ctx)
+}
+
+// refused reports whether the crawl doesn't make the request of a tab to link, counting it against -max-requests
+func (rd *renderer) refused(link string) bool {
+	cr := rd.cr
+
+	if cr.denied(link) {
+		if cr.cfg.Verbose {
+			if _, loaded := cr.denyReported.LoadOrStore(link, true); !loaded {
+				cr.logf("Denied: %s\n", link)
+			}
+		}
+		return true
+	}
+
+	if u, err := url.Parse(link); err != nil || (cr.cfg.Infra != nil && !cr.onAllowedInfra(u.Hostname())) {
+		return true
+	}
+
+	if !cr.cfg.IgnoreRobots && cr.inScope(link, 0) && !cr.robotsAllowed(link) {
+		return true
+	}
+
+	return cr.cfg.MaxRequests > 0 && cr.overBudget()
+}
+
+// forward makes the request of a tab with the crawl's transport and returns the action answering the tab
+func (rd *renderer) forward(ev *fetch.EventRequestPaused) chromedp.Action {
+	var body bytes.Buffer
+
+	for _, entry := range ev.Request.PostDataEntries {
+		data, err := base64.StdEncoding.DecodeString(entry.Bytes)
+		if err != nil {
+			return fetch.FailRequest(ev.RequestID, network.ErrorReasonFailed)
+		}
+		body.Write(data)
+	}
+
+	req, err := http.NewRequestWithContext(rd.cr.ctx, ev.Request.Method, ev.Request.URL, &body)
+	if err != nil {
+		return fetch.FailRequest(ev.RequestID, network.ErrorReasonFailed)
+	}
+
+	for header, value := range ev.Request.Headers {
+		if s, ok := value.(string); ok {
+			req.Header.Set(header, s)
+		}
+	}
+
+	// let the transport pick the encoding and decode the body
+	req.Header.Del("Accept-Encoding")
+
+	resp, err := rd.client.Do(req)
+	if err != nil {
+		if errors.Is(err, errDenied) {
+			return fetch.FailRequest(ev.RequestID, network.ErrorReasonBlockedByClient)
+		}
+		return fetch.FailRequest(ev.RequestID, network.ErrorReasonFailed)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fetch.FailRequest(ev.RequestID, network.ErrorReasonFailed)
+	}
+
+	return fulfill(ev.RequestID, resp.StatusCode, resp.Header, data, false)
+}
+
+// fulfill answers a paused request with a response, without the Content-Encoding of a decoded body
+func fulfill(id fetch.RequestID, status int, header http.Header, body []byte, decoded bool) chromedp.Action {
+	headers := make([]*fetch.HeaderEntry, 0, len(header))
+
+	for name, values := range header {
+		if strings.EqualFold(name, "Content-Length") || (decoded && strings.EqualFold(name, "Content-Encoding")) {
+			continue
+		}
+
+		for _, value := range values {
+			headers = append(headers, &fetch.HeaderEntry{Name: name, Value: value})
+		}
+	}
+
+	return fetch.FulfillRequest(id, int64(status)).WithResponseHeaders(headers).WithBody(base64.StdEncoding.EncodeToString(body))
+}
+
+// cookies returns the cookies of the crawl's jar for link, the browser has its own jar
+func (rd *renderer) cookies(link string) []*network.CookieParam {
+	u, err := url.Parse(link)
+
+	if err != nil || rd.cr.client == nil || rd.cr.client.Jar == nil {
+		return nil
+	}
+
+	params := make([]*network.CookieParam, 0)
+
+	for _, cookie := range rd.cr.client.Jar.Cookies(u) {
+		params = append(params, &network.CookieParam{Name: cookie.Name, Value: cookie.Value, URL: link})
+	}
+
+	return params
+}
+
+// isHTML reports whether the response is an HTML page, colly only parses those, so only they are rendered
+func isHTML(r *colly.Response) bool {
+	return strings.Contains(strings.ToLower(r.Headers.Get("Content-Type")), "html")
+}
@@ -0,0 +1,96 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/gocolly/colly"
+)
+
+func TestSettle(t *testing.T) {
+	calls := 0
+	start := time.Now()
+
+	settle(context.Background(), time.Minute, func() bool {
+		calls++
+		return calls == 3
+	})
+
+	if calls != 3 || time.Since(start) > 5*time.Second {
+		t.Errorf("settle returned after %d checks and %v, want 3 checks", calls, time.Since(start))
+	}
+
+	start = time.Now()
+	settle(context.Background(), 300*time.Millisecond, func() bool { return false })
+
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond || elapsed > 5*time.Second {
+		t.Errorf("settle waited %v for a page that never settles, want 300ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start = time.Now()
+	settle(ctx, time.Minute, func() bool { return false })
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("settle waited %v after its context was done", elapsed)
+	}
+}
+
+func TestRenderRefused(t *testing.T) {
+	cr := &crawl{cfg: &Config{
+		DenyRegex:    []*regexp.Regexp{regexp.MustCompile(`/admin/`)},
+		IgnoreRobots: true,
+		MaxRequests:  2,
+	}}
+	rd := newRenderer(cr, time.Second)
+
+	if !rd.refused("https://x.com/admin/users") {
+		t.Error("a -deny link was requested")
+	}
+
+	if rd.refused("https://x.com/a.js") || rd.refused("https://x.com/b.js") {
+		t.Error("the requests within -max-requests were refused")
+	}
+
+	if !rd.refused("https://x.com/c.js") {
+		t.Error("a request past -max-requests was made")
+	}
+}
+
+func TestRenderAssetsNotDelayed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("body{}"))
+	}))
+	defer server.Close()
+
+	cr := &crawl{ctx: context.Background(), cfg: &Config{Threads: 1, Delay: time.Second}, rng: newLockedRand(0)}
+	if _, err := cr.setTransport(colly.NewCollector()); err != nil {
+		t.Fatal(err)
+	}
+
+	rd := newRenderer(cr, time.Second)
+	rd.client = tabClient(cr.client.Transport)
+
+	start := time.Now()
+
+	for i := 0; i < 5; i++ {
+		ev := &fetch.EventRequestPaused{RequestID: fetch.RequestID(strconv.Itoa(i)), Request: &network.Request{Method: "GET", URL: server.URL + "/style.css"}}
+
+		if _, ok := rd.forward(ev).(*fetch.FulfillRequestParams); !ok {
+			t.Fatalf("asset %d wasn't fulfilled", i)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed > 900*time.Millisecond {
+		t.Errorf("5 assets took %v with -delay 1000, the assets of a page must not wait for it", elapsed)
+	}
+}
@@ -0,0 +1,227 @@
+package crawler
+
+import (
+	"sync"
+
+	"github.com/gocolly/colly"
+)
+
+// Result is a single URL discovered by the crawler
+type Result struct {
+	// Position of the URL in discovery order, starting at 1
+	Index int `json:"index"`
+
+	// How many times the URL was referenced during the crawl
+	Count int `json:"count"`
+
+	// The absolute URL
+	URL string `json:"url"`
+
+	// The page the URL was found on
+	Source string `json:"source"`
+
+	// The depth of that page, the links of the input URL have depth 1. 0 when the URL wasn't found on a page (sitemaps)
+	Depth int `json:"depth"`
+
+	// What referenced the URL (href, area, script, form, link, img, source, srcset, iframe, frame, embed, object, meta-refresh, base, comment, redirect, js, manifest, service-worker, sitemap, robots, archive, xhr, module, link-header, json-ld or microdata)
+	Type string `json:"type"`
+
+	// Whether the anchor carries a download attribute
+	Download bool `json:"download,omitempty"`
+
+	// The filename suggested by the download attribute, if any
+	Filename string `json:"filename,omitempty"`
+
+	// The HTTP status of the URL with -verify or -show-status, 0 when it couldn't be requested
+	Status int `json:"status,omitempty"`
+
+	// With -show-status, the content type and body length of the visited page,
+	// and the URLs it was redirected through, the last one answered
+	ContentType string   `json:"content_type,omitempty"`
+	Length      int      `json:"length,omitempty"`
+	Redirects   []string `json:"redirects,omitempty"`
+
+	// The target and rel attributes of the anchor
+	Target string `json:"target,omitempty"`
+	Rel    string `json:"rel,omitempty"`
+
+	// With -tabnabbing, whether the anchor opens a new window without rel="noopener"
+	Tabnabbing bool `json:"tabnabbing,omitempty"`
+
+	// Whether the page answered 304 Not Modified to the validators of -validators
+	Unchanged bool `json:"unchanged,omitempty"`
+
+	// The SHA-256 of the body of the page with -hash, empty when it wasn't fetched
+	Hash string `json:"hash,omitempty"`
+
+	// The <meta name="robots"> and X-Robots-Tag directives of the page, with -robots-meta
+	MetaRobots string `json:"meta_robots,omitempty"`
+	XRobotsTag string `json:"x_robots_tag,omitempty"`
+
+	// With -forms, the method and named fields of a form
+	Method string  `json:"method,omitempty"`
+	Inputs []Input `json:"inputs,omitempty"`
+
+	// With -forms, the names of the query parameters of the URL and of the fields of a form, sorted
+	Params []string `json:"params,omitempty"`
+}
+
+// resultSet collects the unique results of a crawl, it's safe for concurrent use
+type resultSet struct {
+	mu      sync.Mutex
+	results []Result
+
+	// URL => index of its result
+	seen map[string]int
+
+	// annotations of URLs that weren't recorded (yet)
+	pending map[string][]func(*Result)
+
+	// URLs with the same key are the same result, nil compares them verbatim
+	key func(link string) string
+
+	// keys recorded by every crawl sharing it, nil when the crawl doesn't share
+	shared *URLSet
+}
+
+func newResultSet() *resultSet {
+	return &resultSet{
+		results: make([]Result, 0),
+		seen:    make(map[string]int),
+		pending: make(map[string][]func(*Result)),
+	}
+}
+
+// add appends the result if its URL wasn't seen before and reports whether it did,
+// with the result as recorded
+func (rs *resultSet) add(result Result) (Result, bool) {
+	if result.URL == "" {
+		return result, false
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	key := rs.keyOf(result.URL)
+
+	// Append only unique links, repeats are only counted
+	if i, ok := rs.seen[key]; ok {
+		rs.results[i].Count++
+		return result, false
+	}
+
+	if rs.shared != nil && !rs.shared.Add(key) {
+		return result, false
+	}
+
+	result.Count = 1
+
+	for _, annotate := range rs.pending[key] {
+		annotate(&result)
+	}
+	delete(rs.pending, key)
+
+	// the lock already serializes discoveries, the position is the counter
+	result.Index = len(rs.results) + 1
+
+	rs.seen[key] = len(rs.results)
+	rs.results = append(rs.results, result)
+
+	return result, true
+}
+
+// annotate updates the result of a URL with information found when visiting it.
+// Pages can be visited before they are recorded, then the update waits for the result
+func (rs *resultSet) annotate(link string, update func(*Result)) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	key := rs.keyOf(link)
+
+	if i, ok := rs.seen[key]; ok {
+		update(&rs.results[i])
+	} else {
+		rs.pending[key] = append(rs.pending[key], update)
+	}
+}
+
+// keyOf returns the key the results of a URL are deduplicated by
+func (rs *resultSet) keyOf(link string) string {
+	if rs.key == nil {
+		return link
+	}
+
+	return rs.key(link)
+}
+
+// URLSet is a set of URLs several crawls can share through Config.Shared, it's safe for concurrent use.
+// Its zero value is an empty set
+type URLSet struct {
+	mu   sync.Mutex
+	urls map[string]bool
+}
+
+// NewURLSet returns an empty set
+func NewURLSet() *URLSet {
+	return &URLSet{}
+}
+
+// Add adds link to the set and reports whether it wasn't there
+func (s *URLSet) Add(link string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.urls == nil {
+		s.urls = make(map[string]bool)
+	}
+
+	if s.urls[link] {
+		return false
+	}
+
+	s.urls[link] = true
+
+	return true
+}
+
+// Has reports whether link is in the set
+func (s *URLSet) Has(link string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.urls[link]
+}
+
+// Len returns the number of URLs in the set
+func (s *URLSet) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.urls)
+}
+
+// Reset empties the set, the crawls sharing it record the URLs they find again
+func (s *URLSet) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.urls = nil
+}
+
+// list returns the collected results in discovery order
+func (rs *resultSet) list() []Result {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	return append([]Result(nil), rs.results...)
+}
+
+// newResult builds the result of a link found on the page requested by r
+func newResult(link string, kind string, r *colly.Request) Result {
+	return Result{
+		URL:    r.AbsoluteURL(link),
+		Source: r.URL.String(),
+		Depth:  r.Depth,
+		Type:   kind,
+	}
+}
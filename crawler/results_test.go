@@ -0,0 +1,32 @@
+package crawler
+
+import "testing"
+
+func TestResultCounts(t *testing.T) {
+	rs := newResultSet()
+
+	for _, link := range []string{"https://x.com/a", "https://x.com/b", "https://x.com/a", "", "https://x.com/c", "https://x.com/a", "https://x.com/b"} {
+		rs.add(Result{URL: link})
+	}
+
+	want := []struct {
+		url   string
+		count int
+	}{
+		{"https://x.com/a", 3},
+		{"https://x.com/b", 2},
+		{"https://x.com/c", 1},
+	}
+
+	if len(rs.results) != len(want) {
+		t.Fatalf("%d results, want %d", len(rs.results), len(want))
+	}
+
+	for i, w := range want {
+		got := rs.results[i]
+
+		if got.URL != w.url || got.Count != w.count || got.Index != i+1 {
+			t.Errorf("result %d = %s (index %d) referenced %d times, want %s referenced %d times", i, got.URL, got.Index, got.Count, w.url, w.count)
+		}
+	}
+}
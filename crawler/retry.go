@@ -0,0 +1,151 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// default -retry-codes, statuses that usually go away on their own
+var defaultRetryCodes = []int{429, 500, 502, 503, 504}
+
+// maxRetryAfter is the longest Retry-After waited for, the requests asking for more aren't retried
+const maxRetryAfter = 5 * time.Minute
+
+// retryTransport retries requests that failed or answered one of the retry codes,
+// waiting backoff, then twice as long after every attempt (or what Retry-After asks for)
+type retryTransport struct {
+	next    http.RoundTripper
+	retries int
+	codes   map[int]bool
+	backoff time.Duration
+
+	// where giving up is reported, nowhere when nil
+	log io.Writer
+
+	// called before waiting for each retry, with the response or error that caused it
+	onRetry func(req *http.Request, attempt int, resp *http.Response, err error, wait time.Duration)
+}
+
+func newRetryTransport(next http.RoundTripper, retries int, codes []int, backoff time.Duration) *retryTransport {
+	t := &retryTransport{next: next, retries: retries, codes: make(map[int]bool), backoff: backoff}
+
+	for _, code := range codes {
+		t.codes[code] = true
+	}
+
+	return t
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	wait := t.backoff
+
+	for attempt := 0; ; attempt++ {
+		out := req
+
+		// bodies are consumed by every attempt
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+
+			out = req.Clone(req.Context())
+			out.Body = body
+		}
+
+		resp, err := t.next.RoundTrip(out)
+
+		retryable := err != nil || t.codes[resp.StatusCode]
+		rewindable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+		retry := retryable && attempt < t.retries && rewindable && req.Context().Err() == nil
+		delay, late := retryDelay(req.Context(), resp, wait)
+
+		if !retry || late {
+			// say what the crawl is missing
+			if retryable && t.log != nil {
+				reason := fmt.Sprint(err)
+				if err == nil {
+					reason = resp.Status
+				}
+
+				switch {
+				case retry:
+					fmt.Fprintf(t.log, "Giving up on %s: %s asks for a retry in %v\n", req.URL, reason, delay)
+				case attempt > 0:
+					fmt.Fprintf(t.log, "Giving up on %s after %d retries: %s\n", req.URL, attempt, reason)
+				}
+			}
+
+			if resp != nil {
+				resp.Request = req
+			}
+
+			return resp, err
+		}
+
+		if t.onRetry != nil {
+			t.onRetry(req, attempt+1, resp, err, delay)
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+
+		wait *= 2
+	}
+}
+
+// retryDelay returns the wait before the next retry, what Retry-After asks for over the backoff. It reports
+// whether that's past maxRetryAfter or the deadline of ctx, e.g. -max-time, waiting would be for nothing then
+func retryDelay(ctx context.Context, resp *http.Response, backoff time.Duration) (time.Duration, bool) {
+	delay := backoff
+
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			delay = time.Duration(seconds) * time.Second
+
+			if delay > maxRetryAfter {
+				return delay, true
+			}
+		}
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return delay, true
+	}
+
+	return delay, false
+}
+
+// ParseStatuses parses a comma-separated list of HTTP statuses, e.g. "429,503"
+func ParseStatuses(list string) ([]int, error) {
+	codes := make([]int, 0)
+
+	for _, field := range strings.Split(list, ",") {
+		code, err := strconv.Atoi(strings.TrimSpace(field))
+
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("invalid status %q", field)
+		}
+
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
@@ -0,0 +1,76 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryAfterCap(t *testing.T) {
+	var requests int64
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		w.Header().Set("Retry-After", r.URL.Query().Get("after"))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		after    string
+		deadline time.Duration
+		want     int64
+		log      string
+	}{
+		{"within the cap", "0", 0, 3, "after 2 retries"},
+		{"past the cap", "3600", 0, 1, "asks for a retry in 1h0m0s"},
+		{"past the deadline", "5", time.Second, 1, "asks for a retry in 5s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt64(&requests, 0)
+
+			var log strings.Builder
+			retry := newRetryTransport(http.DefaultTransport, 2, defaultRetryCodes, time.Millisecond)
+			retry.log = &log
+
+			ctx := context.Background()
+			if tt.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.deadline)
+				defer cancel()
+			}
+
+			req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"/?after="+tt.after, nil)
+
+			start := time.Now()
+			resp, err := retry.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != http.StatusServiceUnavailable {
+				t.Errorf("status %d, want the 503 of the server", resp.StatusCode)
+			}
+
+			if got := atomic.LoadInt64(&requests); got != tt.want {
+				t.Errorf("%d requests, want %d", got, tt.want)
+			}
+
+			if time.Since(start) > 5*time.Second {
+				t.Errorf("waited %v", time.Since(start))
+			}
+
+			if !strings.Contains(log.String(), tt.log) {
+				t.Errorf("log %q, want %q in it", log.String(), tt.log)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package crawler
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/temoto/robotstxt"
+)
+
+// robotsAgent is the user agent robots.txt groups are matched against,
+// the requests themselves keep the browser user agent
+const robotsAgent = "RockRawler"
+
+// robotsFile is the robots.txt of an origin, fetched once
+type robotsFile struct {
+	once sync.Once
+	data *robotstxt.RobotsData
+
+	// the paths of its Allow and Disallow rules, for -robots
+	paths []string
+}
+
+// robotsAllowed reports whether the robots.txt of its origin lets the crawl request link.
+// Origins without a readable robots.txt allow everything, like a 4xx does
+func (cr *crawl) robotsAllowed(link string) bool {
+	u, err := url.Parse(link)
+
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return true
+	}
+
+	data := cr.robotsOf(u.Scheme + "://" + u.Host)
+
+	return data == nil || data.TestAgent(u.RequestURI(), robotsAgent)
+}
+
+// robotsOf returns the robots.txt of an origin like https://example.com, fetching it the first time
+func (cr *crawl) robotsOf(origin string) *robotstxt.RobotsData {
+	return cr.robotsFileOf(origin).data
+}
+
+func (cr *crawl) robotsFileOf(origin string) *robotsFile {
+	entry, _ := cr.robots.LoadOrStore(origin, &robotsFile{})
+	file := entry.(*robotsFile)
+
+	file.once.Do(func() {
+		cr.fetchRobots(origin, file)
+	})
+
+	return file
+}
+
+// fetchRobots requests the robots.txt of an origin, no data means nothing is disallowed
+func (cr *crawl) fetchRobots(origin string, file *robotsFile) {
+	resp, err := cr.get(origin + "/robots.txt")
+
+	if err != nil {
+		return
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 512*1024))
+
+	if err != nil {
+		return
+	}
+
+	if resp.StatusCode == 200 {
+		file.paths = robotsPaths(string(body))
+	}
+
+	// 5xx disallows everything until the site is back, as the robots.txt spec says
+	if data, err := robotstxt.FromStatusAndBytes(resp.StatusCode, body); err == nil {
+		file.data = data
+	}
+}
+
+// robotsPaths returns the paths of the Allow and Disallow rules of every group of a robots.txt.
+// Patterns are cut at their first wildcard, "/admin/*.php" is "/admin/"
+func robotsPaths(body string) []string {
+	paths := make([]string, 0)
+
+	for _, line := range strings.Split(body, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		key, value, ok := strings.Cut(line, ":")
+
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !ok || (key != "allow" && key != "disallow") {
+			continue
+		}
+
+		path := strings.TrimSpace(value)
+		if i := strings.IndexAny(path, "*$"); i >= 0 {
+			path = path[:i]
+		}
+
+		// the root is the target itself
+		if strings.HasPrefix(path, "/") && path != "/" {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths
+}
+
+// robotsSeeds records the paths robots.txt lists for the origin of link, and returns the in-scope ones.
+// The disallowed ones are only requested with -ignore-robots
+func (cr *crawl) robotsSeeds(link string) []string {
+	u, err := url.Parse(link)
+
+	if err != nil {
+		return nil
+	}
+
+	robots := u.Scheme + "://" + u.Host + "/robots.txt"
+	seeds := make([]string, 0)
+
+	for _, path := range cr.robotsFileOf(u.Scheme + "://" + u.Host).paths {
+		found := lowerHost(resolveLoc(robots, path))
+
+		cr.addResult(Result{URL: found, Source: robots, Type: "robots"})
+
+		if found != "" && cr.seedable(found) {
+			seeds = append(seeds, found)
+		}
+	}
+
+	return seeds
+}
+
+// reportRobots writes the URLs robots.txt keeps out to the log, once each
+func (cr *crawl) reportRobots(link string) {
+	if _, loaded := cr.robotsBlocked.LoadOrStore(link, true); !loaded {
+		cr.logf("Disallowed by robots.txt: %s\n", link)
+	}
+}
@@ -0,0 +1,53 @@
+package crawler
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// lockedRand is a seedable random source safe for concurrent use, rand.Rand isn't.
+// Every random choice of a crawl draws from its source, so -seed reproduces them all
+type lockedRand struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// newLockedRand seeds the source with seed, or with the current time when seed is 0
+func newLockedRand(seed int64) *lockedRand {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return &lockedRand{rnd: rand.New(rand.NewSource(seed))}
+}
+
+func (l *lockedRand) Float64() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.rnd.Float64()
+}
+
+func (l *lockedRand) Int63() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.rnd.Int63()
+}
+
+func (l *lockedRand) Int63n(n int64) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.rnd.Int63n(n)
+}
+
+// sampled reports whether a discovered link is part of the -sample fraction that gets followed
+func (cr *crawl) sampled() bool {
+	if cr.cfg.Sample <= 0 || cr.cfg.Sample >= 1 {
+		return true
+	}
+
+	return cr.rng.Float64() < cr.cfg.Sample
+}
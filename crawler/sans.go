@@ -0,0 +1,56 @@
+package crawler
+
+import (
+	"net"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// DefaultMaxHosts is the default of -max-hosts
+const DefaultMaxHosts = 10
+
+// collectSANs queues the SAN hostnames of a certificate that belong to the target's
+// organization (same registrable domain) as new seeds, up to -max-hosts per target
+func (cr *crawl) collectSANs(info TLSInfo) {
+	org := orgDomain(cr.hostname)
+
+	cr.sanMu.Lock()
+	defer cr.sanMu.Unlock()
+
+	for _, san := range info.SANs {
+		// a wildcard stands for its parent domain
+		host := strings.ToLower(strings.TrimPrefix(san, "*."))
+
+		if net.ParseIP(host) != nil || host == cr.hostname || cr.sanHosts[host] || orgDomain(host) != org {
+			continue
+		}
+
+		if len(cr.sanHosts) >= cr.cfg.MaxHosts {
+			return
+		}
+
+		cr.sanHosts[host] = true
+		cr.sanSeeds = append(cr.sanSeeds, host)
+	}
+}
+
+// takeSANSeeds returns the seeds queued since the last call
+func (cr *crawl) takeSANSeeds() []string {
+	cr.sanMu.Lock()
+	defer cr.sanMu.Unlock()
+
+	seeds := cr.sanSeeds
+	cr.sanSeeds = nil
+
+	return seeds
+}
+
+// orgDomain returns the registrable domain of a hostname (e.g. example.co.uk), or the hostname itself
+func orgDomain(hostname string) string {
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(hostname); err == nil {
+		return domain
+	}
+
+	return hostname
+}
@@ -0,0 +1,227 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+)
+
+// ScopeExpr is a compiled -scope-expr expression.
+// The expression sees the following attributes of every candidate link:
+//
+//	url    string  the absolute URL
+//	scheme string  e.g. "https"
+//	host   string  hostname without port
+//	port   int     explicit port, or the default port of the scheme
+//	path   string  e.g. "/api/users"
+//	query  string  raw query string without "?"
+//	depth  int     depth the link would be crawled at
+type ScopeExpr struct {
+	prg cel.Program
+}
+
+// CompileScopeExpr parses and type-checks a CEL expression, it must evaluate to a bool
+func CompileScopeExpr(expr string) (*ScopeExpr, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("url", cel.StringType),
+		cel.Variable("scheme", cel.StringType),
+		cel.Variable("host", cel.StringType),
+		cel.Variable("port", cel.IntType),
+		cel.Variable("path", cel.StringType),
+		cel.Variable("query", cel.StringType),
+		cel.Variable("depth", cel.IntType),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ast, issues := env.Compile(expr)
+
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+
+	if ast.OutputType() != cel.BoolType {
+		return nil, errors.New("scope expression must evaluate to a bool")
+	}
+
+	prg, err := env.Program(ast)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScopeExpr{prg: prg}, nil
+}
+
+// Allows reports whether the link is in scope, links that fail to parse or evaluate are not
+func (s *ScopeExpr) Allows(link string, depth int) bool {
+	u, err := url.Parse(link)
+
+	if err != nil || link == "" {
+		return false
+	}
+
+	out, _, err := s.prg.Eval(map[string]interface{}{
+		"url":    link,
+		"scheme": u.Scheme,
+		"host":   strings.ToLower(u.Hostname()),
+		"port":   effectivePort(u),
+		"path":   u.Path,
+		"query":  u.RawQuery,
+		"depth":  depth,
+	})
+
+	if err != nil {
+		return false
+	}
+
+	allowed, ok := out.Value().(bool)
+
+	return ok && allowed
+}
+
+// effectivePort returns the port of the URL, falling back to the default port of its scheme
+func effectivePort(u *url.URL) int {
+	if port, err := strconv.Atoi(u.Port()); err == nil {
+		return port
+	}
+
+	switch u.Scheme {
+	case "http":
+		return 80
+	case "https":
+		return 443
+	}
+
+	return 0
+}
+
+// portAllowed reports whether the (effective) port of the link is one of ports
+func portAllowed(link string, ports []int) bool {
+	u, err := url.Parse(link)
+
+	if err != nil {
+		return false
+	}
+
+	port := effectivePort(u)
+
+	for _, allowed := range ports {
+		if port == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParsePorts parses a comma-separated list of ports, e.g. "80,443,8080"
+func ParsePorts(list string) ([]int, error) {
+	ports := make([]int, 0)
+
+	for _, field := range strings.Split(list, ",") {
+		port, err := strconv.Atoi(strings.TrimSpace(field))
+
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port %q", field)
+		}
+
+		ports = append(ports, port)
+	}
+
+	return ports, nil
+}
+
+// visitable reports whether link passes -include-regex, -exclude-regex and -ignore-ext
+func (cr *crawl) visitable(link string) bool {
+	if cr.cfg.IncludeRegex != nil && !cr.cfg.IncludeRegex.MatchString(link) {
+		return false
+	}
+
+	if cr.cfg.ExcludeRegex != nil && cr.cfg.ExcludeRegex.MatchString(link) {
+		return false
+	}
+
+	return !cr.cfg.IgnoreExt[urlExtension(link)]
+}
+
+// urlExtension returns the lowercased extension of the path of link without its dot, "" without one
+func urlExtension(link string) string {
+	u, err := url.Parse(link)
+
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(strings.TrimPrefix(path.Ext(u.Path), "."))
+}
+
+// ParseExtensions parses a comma-separated list of extensions into a set, e.g. "png,.JPG,woff2".
+// Extensions are lowercased and lose their dot
+func ParseExtensions(list string) (map[string]bool, error) {
+	exts := make(map[string]bool)
+
+	for _, field := range strings.Split(list, ",") {
+		ext := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(field), "."))
+
+		if ext == "" || strings.ContainsAny(ext, "./") {
+			return nil, fmt.Errorf("invalid extension %q", field)
+		}
+
+		exts[ext] = true
+	}
+
+	return exts, nil
+}
+
+// inScope mirrors the scope colly enforces on requests (the target host, its subdomains with -subs,
+// or -scope-expr alone), links outside it are external. depth is the depth the link would be crawled at
+func (cr *crawl) inScope(link string, depth int) bool {
+	if cr.cfg.ScopeExpr != nil {
+		return cr.cfg.ScopeExpr.Allows(link, depth)
+	}
+
+	u, err := url.Parse(link)
+
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+
+	host := strings.ToLower(u.Host)
+	hostname := strings.ToLower(u.Hostname())
+
+	cr.sanMu.Lock()
+	defer cr.sanMu.Unlock()
+
+	for _, allowed := range append([]string{cr.hostname}, mapKeys(cr.sanHosts)...) {
+		// colly compares the host and port exactly
+		if host == allowed {
+			return true
+		}
+
+		// -subs matches the hostname and its subdomains
+		if cr.cfg.SubsInScope && (hostname == allowed || strings.HasSuffix(hostname, "."+allowed)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mapKeys returns the keys of a set
+func mapKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+
+	for key := range set {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
@@ -0,0 +1,79 @@
+package crawler
+
+import "testing"
+
+func TestURLExtension(t *testing.T) {
+	tests := []struct {
+		link string
+		want string
+	}{
+		{"https://x.com/app.js", "js"},
+		{"https://x.com/app.JS", "js"},
+		{"https://x.com/Logo.PnG?v=1", "png"},
+		{"https://x.com/dir.D/page", ""},
+		{"https://x.com/", ""},
+	}
+
+	for _, tt := range tests {
+		if got := urlExtension(tt.link); got != tt.want {
+			t.Errorf("urlExtension(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestIgnoreExtIgnoresCase(t *testing.T) {
+	exts, err := ParseExtensions("png,.JPG")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cr := &crawl{cfg: &Config{IgnoreExt: exts}}
+
+	for link, want := range map[string]bool{
+		"https://x.com/a.png":  false,
+		"https://x.com/a.PNG":  false,
+		"https://x.com/a.jpg":  false,
+		"https://x.com/a.Jpg":  false,
+		"https://x.com/a.JS":   true,
+		"https://x.com/a.html": true,
+	} {
+		if got := cr.visitable(link); got != want {
+			t.Errorf("visitable(%q) = %v, want %v", link, got, want)
+		}
+	}
+}
+
+func TestInScopeMixedCaseHost(t *testing.T) {
+	tests := []struct {
+		name string
+		subs bool
+		link string
+		want bool
+	}{
+		{"same host", false, "https://Example.COM/a", true},
+		{"other host", false, "https://WWW.Example.com/a", false},
+		{"subdomain with -subs", true, "https://WWW.Example.com/a", true},
+		{"lookalike with -subs", true, "https://NotExample.com/a", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := &crawl{cfg: &Config{SubsInScope: tt.subs}, hostname: "example.com"}
+
+			if got := cr.inScope(tt.link, 1); got != tt.want {
+				t.Errorf("inScope(%q) = %v, want %v", tt.link, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScopeExprLowercasesHost(t *testing.T) {
+	expr, err := CompileScopeExpr(`host == "www.example.com"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !expr.Allows("https://WWW.Example.COM/a", 1) {
+		t.Error("the expression should see the host lowercased")
+	}
+}
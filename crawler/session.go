@@ -0,0 +1,79 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+)
+
+// setRawCookies puts the cookies of -cookie in the jar for the host of target, and its subdomains with -subs.
+// They're cookies like the others, the servers can replace them when they rotate the session
+func (cr *crawl) setRawCookies(jar *cookiejar.Jar, target string) error {
+	cookies, err := http.ParseCookie(cr.cfg.RawCookies)
+	if err != nil {
+		return err
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+
+	for _, cookie := range cookies {
+		cookie.Path = "/"
+
+		if cr.cfg.SubsInScope {
+			cookie.Domain = u.Hostname()
+		}
+	}
+
+	jar.SetCookies(&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}, cookies)
+
+	return nil
+}
+
+// login posts -login-data to -login-url before the crawl, the cookies it sets land in the jar of the crawl.
+// A relative login URL is resolved against target, data starting with { is sent as JSON
+func (cr *crawl) login(target string) error {
+	base, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+
+	loginURL, err := base.Parse(cr.cfg.LoginURL)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(cr.ctx, "POST", loginURL.String(), strings.NewReader(cr.cfg.LoginData))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("User-Agent", cr.userAgent())
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if strings.HasPrefix(strings.TrimSpace(cr.cfg.LoginData), "{") {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	for header, value := range cr.headers {
+		req.Header.Set(header, value)
+	}
+
+	// redirects are followed, sessions are often set on the page after the form
+	resp, err := cr.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	return nil
+}
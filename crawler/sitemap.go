@@ -0,0 +1,129 @@
+package crawler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// maxSitemaps bounds the sitemaps read per target, indexes can point to each other
+const maxSitemaps = 100
+
+// sitemapDoc is a <urlset> or a <sitemapindex>, they only differ by their elements
+type sitemapDoc struct {
+	URLs []struct {
+		Loc string `xml:"loc"`
+	} `xml:"url"`
+
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// sitemapSeeds reads the sitemaps of the origin of link (/sitemap.xml and the Sitemap entries
+// of robots.txt, nested indexes included), records their URLs and returns the in-scope ones
+func (cr *crawl) sitemapSeeds(link string) []string {
+	u, err := url.Parse(link)
+
+	if err != nil {
+		return nil
+	}
+
+	origin := u.Scheme + "://" + u.Host
+	queue := []string{origin + "/sitemap.xml"}
+
+	if !cr.cfg.IgnoreRobots {
+		if robots := cr.robotsOf(origin); robots != nil {
+			queue = append(queue, robots.Sitemaps...)
+		}
+	}
+
+	seen := make(map[string]bool)
+	seeds := make([]string, 0)
+
+	for len(queue) > 0 && len(seen) < maxSitemaps {
+		sitemap := queue[0]
+		queue = queue[1:]
+
+		if seen[sitemap] {
+			continue
+		}
+		seen[sitemap] = true
+
+		doc, err := cr.fetchSitemap(sitemap)
+		if err != nil {
+			if cr.cfg.Verbose {
+				cr.logf("Skipping sitemap %s: %v\n", sitemap, err)
+			}
+			continue
+		}
+
+		for _, entry := range doc.Sitemaps {
+			queue = append(queue, resolveLoc(sitemap, entry.Loc))
+		}
+
+		for _, entry := range doc.URLs {
+			loc := lowerHost(resolveLoc(sitemap, entry.Loc))
+
+			cr.addResult(Result{URL: loc, Source: sitemap, Type: "sitemap"})
+
+			if loc != "" && cr.seedable(loc) {
+				seeds = append(seeds, loc)
+			}
+		}
+	}
+
+	return seeds
+}
+
+// fetchSitemap requests and parses a sitemap, gzipped ones (sitemap.xml.gz) included.
+// A missing sitemap is an error, a malformed one is reported and keeps the entries read before the error
+func (cr *crawl) fetchSitemap(link string) (*sitemapDoc, error) {
+	resp, err := cr.get(link)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	var body io.Reader = bufio.NewReader(io.LimitReader(resp.Body, 50*1024*1024))
+
+	// recognize gzip by its magic bytes, servers label it every possible way
+	if magic, _ := body.(*bufio.Reader).Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	doc := &sitemapDoc{}
+
+	if err := xml.NewDecoder(body).Decode(doc); err != nil {
+		cr.logf("Malformed sitemap %s, keeping the %d entries before the error: %v\n", link, len(doc.URLs)+len(doc.Sitemaps), err)
+	}
+
+	return doc, nil
+}
+
+// resolveLoc resolves a <loc> against its sitemap, they should be absolute but aren't always
+func resolveLoc(sitemap string, loc string) string {
+	base, err := url.Parse(sitemap)
+	ref, err2 := url.Parse(strings.TrimSpace(loc))
+
+	if err != nil || err2 != nil {
+		return ""
+	}
+
+	return base.ResolveReference(ref).String()
+}
@@ -0,0 +1,148 @@
+package crawler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/gocolly/colly"
+)
+
+var titleRe = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// soft404Fingerprint describes how a host answers a request for a page that doesn't exist
+type soft404Fingerprint struct {
+	status int
+	size   int
+	hash   [sha256.Size]byte
+	title  string
+}
+
+// soft404Probe fingerprints a host once, fp stays nil if the host answers missing pages properly
+type soft404Probe struct {
+	once sync.Once
+	fp   *soft404Fingerprint
+}
+
+// soft404Detector holds the fingerprints of the crawled hosts and the pages matching them
+type soft404Detector struct {
+	probes sync.Map
+	pages  sync.Map
+}
+
+func newSoft404Fingerprint(status int, body []byte) *soft404Fingerprint {
+	return &soft404Fingerprint{
+		status: status,
+		size:   len(body),
+		hash:   sha256.Sum256(body),
+		title:  pageTitle(body),
+	}
+}
+
+// matches reports whether a response looks like the fingerprinted missing page.
+// Error pages often echo the requested path, so a page with the same status and title
+// whose size is within 10% matches too
+func (fp *soft404Fingerprint) matches(status int, body []byte) bool {
+	if status != fp.status {
+		return false
+	}
+
+	if sha256.Sum256(body) == fp.hash {
+		return true
+	}
+
+	diff := len(body) - fp.size
+	if diff < 0 {
+		diff = -diff
+	}
+
+	return fp.title != "" && pageTitle(body) == fp.title && diff*10 <= fp.size
+}
+
+// pageTitle returns the trimmed <title> of an HTML page
+func pageTitle(body []byte) string {
+	if match := titleRe.FindSubmatch(body); match != nil {
+		return strings.TrimSpace(string(match[1]))
+	}
+
+	return ""
+}
+
+// checkSoft404 marks the page of r as a soft-404 if it matches its host's fingerprint
+func (cr *crawl) checkSoft404(r *colly.Response) {
+	u := r.Request.URL
+
+	v, _ := cr.soft404s.probes.LoadOrStore(u.Host, &soft404Probe{})
+	probe := v.(*soft404Probe)
+
+	probe.once.Do(func() {
+		probe.fp = cr.fingerprintMissingPage(u)
+	})
+
+	if probe.fp != nil && probe.fp.matches(r.StatusCode, r.Body) {
+		cr.soft404s.pages.Store(u.String(), true)
+	}
+}
+
+// fingerprintMissingPage requests a random path on the host of u.
+// Only a successful answer is worth fingerprinting, hosts answering with an error status
+// are handled by colly already, and redirects are left alone so the page they lead to isn't suppressed
+func (cr *crawl) fingerprintMissingPage(u *url.URL) *soft404Fingerprint {
+	probe := url.URL{Scheme: u.Scheme, Host: u.Host, Path: fmt.Sprintf("/%x", cr.rng.Int63())}
+
+	resp, err := cr.get(probe.String())
+
+	if err != nil {
+		return nil
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 || resp.Request.URL.String() != probe.String() {
+		return nil
+	}
+
+	var body bytes.Buffer
+
+	if _, err := io.Copy(&body, io.LimitReader(resp.Body, 10*1024*1024)); err != nil {
+		return nil
+	}
+
+	return newSoft404Fingerprint(resp.StatusCode, body.Bytes())
+}
+
+// isSoft404 reports whether the page at link was detected as a soft-404
+func (d *soft404Detector) isSoft404(link string) bool {
+	_, ok := d.pages.Load(link)
+	return ok
+}
+
+// count returns how many crawled pages were detected as soft-404s
+func (d *soft404Detector) count() int {
+	n := 0
+
+	d.pages.Range(func(_, _ interface{}) bool {
+		n++
+		return true
+	})
+
+	return n
+}
+
+// filter drops the results pointing to soft-404 pages
+func (d *soft404Detector) filter(results []Result) []Result {
+	kept := make([]Result, 0, len(results))
+
+	for _, result := range results {
+		if !d.isSoft404(result.URL) {
+			kept = append(kept, result)
+		}
+	}
+
+	return kept
+}
@@ -0,0 +1,78 @@
+package crawler
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// metaRefreshURL returns the URL of a <meta http-equiv="refresh" content="5; url=/next">, if any
+func metaRefreshURL(content string) string {
+	_, after, ok := strings.Cut(content, ";")
+	if !ok {
+		return ""
+	}
+
+	after = strings.TrimSpace(after)
+	if len(after) < 4 || !strings.EqualFold(after[:3], "url") {
+		return ""
+	}
+
+	after = strings.TrimSpace(after[3:])
+	if !strings.HasPrefix(after, "=") {
+		return ""
+	}
+
+	return strings.Trim(strings.TrimSpace(after[1:]), `"'`)
+}
+
+// srcsetURLs returns the URLs of a srcset, "small.jpg 480w, large.jpg 1080w"
+func srcsetURLs(srcset string) []string {
+	urls := make([]string, 0)
+
+	for _, candidate := range strings.Split(srcset, ",") {
+		if fields := strings.Fields(candidate); len(fields) > 0 {
+			urls = append(urls, fields[0])
+		}
+	}
+
+	return urls
+}
+
+// commentAttrRe matches the links of markup commented out, commentURLRe the absolute URLs written in comments
+var (
+	commentAttrRe = regexp.MustCompile(`(?i)\b(?:href|src|action)\s*=\s*["']([^"'\s]+)["']`)
+	commentURLRe  = regexp.MustCompile(`https?://[^\s"'<>()]+`)
+)
+
+// commentURLs returns the URLs found in the HTML comments below node
+func commentURLs(node *html.Node) []string {
+	urls := make([]string, 0)
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.CommentNode {
+			seen := make(map[string]bool)
+
+			for _, match := range commentAttrRe.FindAllStringSubmatch(n.Data, -1) {
+				seen[match[1]] = true
+				urls = append(urls, match[1])
+			}
+
+			for _, link := range commentURLRe.FindAllString(n.Data, -1) {
+				if !seen[link] {
+					urls = append(urls, link)
+				}
+			}
+		}
+
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+
+	walk(node)
+
+	return urls
+}
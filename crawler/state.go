@@ -0,0 +1,421 @@
+package crawler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"io/fs"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gocolly/colly"
+	"github.com/gocolly/colly/storage"
+)
+
+// stateInterval is how often the crawls write a checkpoint with -state
+const stateInterval = 15 * time.Second
+
+// CrawlState checkpoints the crawls of a run to a file, for -state. A run resuming it picks every crawl up
+// where it stopped: finished crawls return their results again, the others skip the pages they already
+// crawled and request the links that were pending first
+type CrawlState struct {
+	path string
+
+	mu      sync.Mutex
+	targets map[string]*targetState
+}
+
+// targetState is the checkpoint of the crawl of a target
+type targetState struct {
+	Target string `json:"target"`
+
+	// whether the crawl ended by itself, not cancelled nor stopped by -fail-fast or -max-requests
+	Done bool `json:"done"`
+
+	// the pages crawled, and the links queued or in flight
+	Visited []string      `json:"visited"`
+	Pending []pendingLink `json:"pending"`
+
+	// the deepest level whose pages were all crawled, a checkpoint is written as each one ends
+	Depth int `json:"depth"`
+
+	Results []Result `json:"results"`
+}
+
+// pendingLink is a link waiting to be visited, at the depth its request gets
+type pendingLink struct {
+	URL   string `json:"url"`
+	Depth int    `json:"depth"`
+}
+
+// NewCrawlState returns an empty state checkpointed to path
+func NewCrawlState(path string) *CrawlState {
+	return &CrawlState{path: path, targets: make(map[string]*targetState)}
+}
+
+// LoadCrawlState reads the state a previous run checkpointed to path, a missing file is an empty state
+func LoadCrawlState(path string) (*CrawlState, error) {
+	state := NewCrawlState(path)
+
+	data, err := os.ReadFile(path)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Targets []*targetState `json:"targets"`
+	}
+
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	for _, target := range file.Targets {
+		state.targets[target.Target] = target
+	}
+
+	return state, nil
+}
+
+// get returns the checkpoint of a target, nil when it has none
+func (s *CrawlState) get(target string) *targetState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.targets[target]
+}
+
+// put replaces the checkpoint of a target and saves the state
+func (s *CrawlState) put(state *targetState) error {
+	s.mu.Lock()
+	s.targets[state.Target] = state
+	s.mu.Unlock()
+
+	return s.Save()
+}
+
+// Save writes the state to its file. It goes through a temporary file, an interrupted save keeps the previous one
+func (s *CrawlState) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	targets := make([]*targetState, 0, len(s.targets))
+	for _, target := range s.targets {
+		targets = append(targets, target)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Target < targets[j].Target })
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(struct {
+		Targets []*targetState `json:"targets"`
+	}{targets}); err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, s.path)
+}
+
+// crawlProgress follows the requests of a crawl for its checkpoints, it's safe for concurrent use
+type crawlProgress struct {
+	mu sync.Mutex
+
+	// URL of a request => the links handed to colly for it, as colly remembers them once visited,
+	// and the depth their request gets (0 keeps colly's). Until the request starts
+	scheduled map[string][]pendingLink
+
+	// request ID => the link requested, until its page is done
+	started map[uint32]pendingLink
+
+	// pages done, in the order they finished
+	visited []string
+
+	// depth => links scheduled or started at it, and the deepest level with none left
+	levels   map[int]int
+	complete int
+}
+
+func newCrawlProgress() *crawlProgress {
+	return &crawlProgress{scheduled: make(map[string][]pendingLink), started: make(map[uint32]pendingLink), levels: make(map[int]int)}
+}
+
+// add counts a link pending at depth, a level it reopens isn't complete anymore
+func (p *crawlProgress) add(depth int) {
+	p.levels[depth]++
+
+	if depth <= p.complete {
+		p.complete = depth - 1
+	}
+}
+
+// remove forgets a link pending at depth, it reports whether a level got complete
+func (p *crawlProgress) remove(depth int) bool {
+	if p.levels[depth]--; p.levels[depth] <= 0 {
+		delete(p.levels, depth)
+	}
+
+	// the crawl is over when nothing is left, its last checkpoint follows anyway
+	if len(p.levels) == 0 {
+		return false
+	}
+
+	lowest := -1
+	for level := range p.levels {
+		if lowest < 0 || level < lowest {
+			lowest = level
+		}
+	}
+
+	if lowest-1 > p.complete {
+		p.complete = lowest - 1
+		return true
+	}
+
+	return false
+}
+
+// requestKey is the URL of the request colly makes for link
+func requestKey(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+
+	if u.Scheme == "" {
+		u.Scheme = "http"
+	}
+
+	return u.String()
+}
+
+// schedule notes that link is about to be handed to colly, it returns the key unschedule takes
+func (p *crawlProgress) schedule(link string, depth int) string {
+	key := requestKey(link)
+
+	p.mu.Lock()
+	p.scheduled[key] = append(p.scheduled[key], pendingLink{URL: link, Depth: depth})
+	p.add(depth)
+	p.mu.Unlock()
+
+	return key
+}
+
+// unschedule forgets a link colly refused, e.g. because it was already visited.
+// It reports whether a level got complete
+func (p *crawlProgress) unschedule(key string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	links := p.scheduled[key]
+	if len(links) == 0 {
+		return false
+	}
+
+	link := links[len(links)-1]
+
+	if len(links) > 1 {
+		p.scheduled[key] = links[:len(links)-1]
+	} else {
+		delete(p.scheduled, key)
+	}
+
+	return p.remove(link.Depth)
+}
+
+// request notes a GET request of colly and gives it the depth it was scheduled at,
+// requests aborted before they're sent stay pending
+func (p *crawlProgress) request(r *colly.Request) {
+	if r.Method != http.MethodGet {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := r.URL.String()
+
+	links := p.scheduled[key]
+	if len(links) == 0 {
+		// not handed over by visit, it's counted from now on
+		p.started[r.ID] = pendingLink{URL: key, Depth: r.Depth}
+		p.add(r.Depth)
+		return
+	}
+
+	link := links[0]
+
+	if len(links) > 1 {
+		p.scheduled[key] = links[1:]
+	} else {
+		delete(p.scheduled, key)
+	}
+
+	r.Depth = link.Depth
+	p.started[r.ID] = link
+}
+
+// finish notes that the page of r was done, crawled, failed or skipped.
+// It reports whether a level got complete
+func (p *crawlProgress) finish(r *colly.Request) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	link, ok := p.started[r.ID]
+	if !ok {
+		return false
+	}
+
+	delete(p.started, r.ID)
+	p.visited = append(p.visited, link.URL)
+
+	return p.remove(link.Depth)
+}
+
+// snapshot returns the pages done, the ones still pending (started, queued or about to be)
+// and the deepest complete level
+func (p *crawlProgress) snapshot() ([]string, []pendingLink, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pending := make([]pendingLink, 0, len(p.started)+len(p.scheduled))
+	for _, link := range p.started {
+		pending = append(pending, link)
+	}
+
+	for _, links := range p.scheduled {
+		pending = append(pending, links...)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].URL < pending[j].URL })
+
+	return append([]string(nil), p.visited...), pending, p.complete
+}
+
+// checkpoint saves the progress of the crawl of target, with the results of done crawls as they're returned
+func (cr *crawl) checkpoint(target string, results []Result, done bool) {
+	visited, pending, depth := cr.progress.snapshot()
+
+	state := &targetState{Target: target, Done: done, Visited: visited, Pending: pending, Depth: depth, Results: results}
+
+	if err := cr.cfg.State.put(state); err != nil {
+		cr.logf("Could not save the crawl state: %v\n", err)
+	}
+}
+
+// checkpoints saves the progress of the crawl of target every stateInterval until the returned function is called
+func (cr *crawl) checkpoints(target string) func() {
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(stateInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				cr.checkpoint(target, cr.results.list(), false)
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-stopped
+	}
+}
+
+// finishLevel checkpoints the crawl when a level got complete
+func (cr *crawl) finishLevel(complete bool) {
+	if complete {
+		cr.checkpoint(cr.target, cr.results.list(), false)
+	}
+}
+
+// skip aborts r for good, e.g. for robots.txt or -deny. Unlike the requests of a stopped crawl,
+// the page isn't pending anymore
+func (cr *crawl) skip(r *colly.Request) {
+	r.Abort()
+
+	if cr.progress != nil {
+		cr.finishLevel(cr.progress.finish(r))
+	}
+}
+
+// complete reports whether the crawl ended by itself, a resumed run has nothing left to crawl then
+func (cr *crawl) complete() bool {
+	if cr.cancelled() || atomic.LoadInt32(&cr.failed) != 0 {
+		return false
+	}
+
+	return cr.cfg.MaxRequests <= 0 || atomic.LoadInt64(&cr.requests) <= int64(cr.cfg.MaxRequests)
+}
+
+// resume picks the crawl up from its checkpoint: the results are recorded again, colly skips the pages
+// already crawled and the pending links are visited again at their depth
+func (cr *crawl) resume(c *colly.Collector, jar *cookiejar.Jar, saved *targetState) {
+	for _, result := range saved.Results {
+		if recorded, ok := cr.results.add(result); ok && cr.cfg.OnResult != nil {
+			cr.cfg.OnResult(recorded)
+		}
+	}
+
+	// a storage of our own lets us mark the visited pages, it brings a jar of its own too
+	store := &storage.InMemoryStorage{}
+	if err := c.SetStorage(store); err != nil {
+		cr.logf("Could not resume the crawl of %s, starting over: %v\n", cr.hostname, err)
+		return
+	}
+	c.SetCookieJar(jar)
+
+	// the checkpoints of this run carry them on
+	for _, link := range saved.Visited {
+		store.Visited(visitID(link))
+		cr.progress.visited = append(cr.progress.visited, link)
+	}
+
+	// the levels done aren't crawled again, the crawl goes on from the first one left
+	cr.progress.complete = saved.Depth
+
+	if cr.cfg.Verbose && saved.Depth > 0 {
+		cr.logf("Resuming the crawl of %s after depth %d\n", cr.hostname, saved.Depth)
+	}
+
+	for _, link := range saved.Pending {
+		// a lower -d leaves the deeper ones out
+		if cr.cfg.Depth > 0 && link.Depth > cr.cfg.Depth {
+			continue
+		}
+
+		cr.visit(c, nil, link.URL, link.Depth)
+	}
+}
+
+// visitID is the ID colly remembers a visited GET request by
+func visitID(link string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(link))
+
+	return h.Sum64()
+}
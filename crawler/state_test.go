@@ -0,0 +1,84 @@
+package crawler
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gocolly/colly"
+	"github.com/gocolly/colly/storage"
+)
+
+func TestVisitID(t *testing.T) {
+	c := colly.NewCollector()
+
+	store := &storage.InMemoryStorage{}
+	if err := c.SetStorage(store); err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing listens there, the visit must be refused before any request
+	visited := "http://127.0.0.1:1/page?id=1"
+	store.Visited(visitID(visited))
+
+	if err := c.Visit(visited); !errors.Is(err, colly.ErrAlreadyVisited) {
+		t.Errorf("Visit of a page marked visited = %v, want %v", err, colly.ErrAlreadyVisited)
+	}
+
+	if err := c.Visit("http://127.0.0.1:1/other"); errors.Is(err, colly.ErrAlreadyVisited) {
+		t.Error("Visit of a page that wasn't marked was refused as already visited")
+	}
+}
+
+func TestResume(t *testing.T) {
+	server, times := chainServer(4)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	target := server.URL + "/0"
+
+	// the first run stops after 2 pages, /2 is pending
+	cfg := &Config{Threads: 1, Depth: 6, SubsInScope: true, IgnoreRobots: true, Timeout: 2 * time.Second, MaxRequests: 2, State: NewCrawlState(path)}
+	StartCrawler(target, cfg)
+
+	state, err := LoadCrawlState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved := state.get(target)
+	if saved == nil {
+		t.Fatal("no checkpoint of the target")
+	}
+
+	if saved.Done || saved.Depth != 2 || len(saved.Visited) != 2 {
+		t.Errorf("checkpoint done %v at depth %d with %d pages visited, want a stopped crawl at depth 2 with 2 pages", saved.Done, saved.Depth, len(saved.Visited))
+	}
+
+	if want := []pendingLink{{server.URL + "/2", 3}}; len(saved.Pending) != 1 || saved.Pending[0] != want[0] {
+		t.Errorf("pending links %v, want %v", saved.Pending, want)
+	}
+
+	// the resumed run requests the pending page and the rest of the chain, nothing twice
+	cfg = &Config{Threads: 1, Depth: 6, SubsInScope: true, IgnoreRobots: true, Timeout: 2 * time.Second, State: state}
+	results := StartCrawler(target, cfg)
+
+	if requested := len(times()); requested != 5 {
+		t.Errorf("%d pages requested by both runs, want the 5 of the chain once each", requested)
+	}
+
+	if len(results) != 4 {
+		t.Errorf("%d results after resuming, want the 4 links of the chain", len(results))
+	}
+
+	for _, result := range results {
+		if result.URL == server.URL+"/4" && result.Depth != 4 {
+			t.Errorf("the last page was found at depth %d, want 4", result.Depth)
+		}
+	}
+
+	if saved = state.get(target); !saved.Done || len(saved.Pending) != 0 {
+		t.Errorf("checkpoint after resuming done %v with %d links pending, want a finished crawl", saved.Done, len(saved.Pending))
+	}
+}
@@ -0,0 +1,48 @@
+package crawler
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/gocolly/colly"
+)
+
+// Stats sums up the crawl of a target
+type Stats struct {
+	Host string
+
+	// unique URLs recorded
+	URLs int
+
+	// requests that got a response or failed, and the failed ones
+	Requests int64
+	Errors   int64
+
+	Elapsed time.Duration
+}
+
+// StartCrawlerStats crawls like StartCrawlerContext and also returns the stats of the crawl
+func StartCrawlerStats(ctx context.Context, url string, cfg *Config) ([]Result, Stats) {
+	stats := Stats{Host: TargetHost(url)}
+	start := time.Now()
+
+	results := startCrawler(ctx, url, cfg, &stats)
+
+	stats.URLs = len(results)
+	stats.Elapsed = time.Since(start)
+
+	return results, stats
+}
+
+// countRequests adds every request of the collector to stats, aborted requests aren't made
+func countRequests(c *colly.Collector, stats *Stats) {
+	c.OnResponse(func(r *colly.Response) {
+		atomic.AddInt64(&stats.Requests, 1)
+	})
+
+	c.OnError(func(r *colly.Response, err error) {
+		atomic.AddInt64(&stats.Requests, 1)
+		atomic.AddInt64(&stats.Errors, 1)
+	})
+}
@@ -0,0 +1,82 @@
+package crawler
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gocolly/colly"
+)
+
+// statusTracker records the status, content type, length and redirects of the visited pages for -show-status.
+// colly rewrites the URL of a request that was redirected, the result is recorded under the URL requested first
+type statusTracker struct {
+	results *resultSet
+
+	// request ID => URL requested before the redirects
+	requests sync.Map
+
+	// URL requested => URLs it was redirected to, in order
+	redirects sync.Map
+}
+
+// track registers the hooks of the tracker on c
+func (st *statusTracker) track(c *colly.Collector) {
+	c.OnRequest(func(r *colly.Request) {
+		st.requests.Store(r.ID, r.URL.String())
+	})
+
+	c.OnResponse(func(r *colly.Response) {
+		st.annotate(r)
+	})
+
+	// statuses from 203 on are errors to colly, the network errors have no status
+	c.OnError(func(r *colly.Response, err error) {
+		if r.StatusCode != 0 {
+			st.annotate(r)
+		} else {
+			st.forget(r.Request)
+		}
+	})
+
+}
+
+// hop adds a followed redirect to the chain of the URL requested first
+func (st *statusTracker) hop(req *http.Request, via []*http.Request) {
+	first := via[0].URL.String()
+	chain, _ := st.redirects.Load(first)
+	hops, _ := chain.([]string)
+	st.redirects.Store(first, append(append([]string(nil), hops...), req.URL.String()))
+}
+
+// annotate records what the request of r returned on the result of the URL it was made for
+func (st *statusTracker) annotate(r *colly.Response) {
+	id, ok := st.requests.LoadAndDelete(r.Request.ID)
+	if !ok {
+		return
+	}
+	requested := id.(string)
+
+	var redirects []string
+	if chain, ok := st.redirects.LoadAndDelete(requested); ok {
+		redirects = chain.([]string)
+	}
+
+	status, contentType, length := r.StatusCode, "", len(r.Body)
+	if r.Headers != nil {
+		contentType = r.Headers.Get("Content-Type")
+	}
+
+	st.results.annotate(requested, func(result *Result) {
+		result.Status = status
+		result.ContentType = contentType
+		result.Length = length
+		result.Redirects = redirects
+	})
+}
+
+// forget drops the state of a request that got no response
+func (st *statusTracker) forget(r *colly.Request) {
+	if requested, ok := st.requests.LoadAndDelete(r.ID); ok {
+		st.redirects.Delete(requested)
+	}
+}
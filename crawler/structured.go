@@ -0,0 +1,106 @@
+package crawler
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/gocolly/colly"
+)
+
+// microdataURLAttrs maps the elements whose itemprop value is a URL to the attribute holding it
+var microdataURLAttrs = map[string]string{
+	"a":      "href",
+	"area":   "href",
+	"link":   "href",
+	"audio":  "src",
+	"embed":  "src",
+	"iframe": "src",
+	"img":    "src",
+	"source": "src",
+	"track":  "src",
+	"video":  "src",
+	"object": "data",
+	"meta":   "content",
+}
+
+// isURLValue reports whether a structured data value looks like a URL
+func isURLValue(value string) bool {
+	return strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") || strings.HasPrefix(value, "/")
+}
+
+// extractJSONLD records the URL-like string values of a JSON-LD block (url, sameAs, image, ...),
+// blocks that aren't valid JSON are ignored
+func (cr *crawl) extractJSONLD(r *colly.Request, source string) {
+	var data interface{}
+
+	if err := json.Unmarshal([]byte(source), &data); err != nil {
+		return
+	}
+
+	for _, value := range jsonStrings(data, nil) {
+		if isURLValue(value) {
+			if !cr.allowLink(r) {
+				return
+			}
+
+			cr.appendResult(value, "json-ld", r)
+		}
+	}
+}
+
+// jsonStrings appends every string value of decoded JSON to values
+func jsonStrings(data interface{}, values []string) []string {
+	switch v := data.(type) {
+	case string:
+		values = append(values, strings.TrimSpace(v))
+	case []interface{}:
+		for _, item := range v {
+			values = jsonStrings(item, values)
+		}
+	case map[string]interface{}:
+		// walk the keys in order, so results come out in the same order every time
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			// the vocabulary isn't part of the data
+			if key != "@context" {
+				values = jsonStrings(v[key], values)
+			}
+		}
+	}
+
+	return values
+}
+
+// extractMicrodata records the URL of a microdata property (an element with itemprop),
+// and its itemid when that's a URL
+func (cr *crawl) extractMicrodata(e *colly.HTMLElement) {
+	values := make([]string, 0, 2)
+
+	if attr, ok := microdataURLAttrs[e.Name]; ok {
+		value := strings.TrimSpace(e.Attr(attr))
+
+		// meta content is free text, only take URLs from it
+		if value != "" && (e.Name != "meta" || isURLValue(value)) {
+			values = append(values, value)
+		}
+	}
+
+	// itemids can be URNs too
+	if itemid := strings.TrimSpace(e.Attr("itemid")); isURLValue(itemid) {
+		values = append(values, itemid)
+	}
+
+	for _, value := range values {
+		if !cr.allowLink(e.Request) {
+			return
+		}
+
+		cr.appendResult(value, "microdata", e.Request)
+	}
+}
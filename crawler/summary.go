@@ -0,0 +1,31 @@
+package crawler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// SummaryHash returns a stable SHA-256 of a result set, two crawls discovering the same URLs
+// hash the same whatever order they found them in. The URLs are normalized (see normalizeURL),
+// deduplicated, sorted and joined with newlines before hashing
+func SummaryHash(results []Result) string {
+	seen := make(map[string]bool)
+	urls := make([]string, 0, len(results))
+
+	for _, result := range results {
+		normalized := normalizeURL(result.URL)
+
+		if !seen[normalized] {
+			seen[normalized] = true
+			urls = append(urls, normalized)
+		}
+	}
+
+	sort.Strings(urls)
+
+	sum := sha256.Sum256([]byte(strings.Join(urls, "\n")))
+
+	return hex.EncodeToString(sum[:])
+}
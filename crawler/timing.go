@@ -0,0 +1,180 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TimingProfile is a -timing-profile, a weighted mix of delay ranges requests are spread with.
+// Every delay picks a range by weight, then a uniform delay inside it. E.g. mostly short
+// pauses while reading a page and now and then a long one mimics someone browsing:
+//
+//	# weight  min    max
+//	8         200ms  1.5s
+//	2         5s     20s
+type TimingProfile struct {
+	ranges []delayRange
+	total  float64
+}
+
+type delayRange struct {
+	weight   float64
+	min, max time.Duration
+}
+
+// LoadTimingProfile reads a profile of one "weight min [max]" range per line, blank lines and # comments are skipped
+func LoadTimingProfile(path string) (*TimingProfile, error) {
+	lines, err := ReadList(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &TimingProfile{}
+
+	for _, line := range lines {
+		fields := strings.Fields(line)
+
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("expected \"weight min [max]\", got %q", line)
+		}
+
+		weight, err := strconv.ParseFloat(fields[0], 64)
+
+		if err != nil || weight <= 0 {
+			return nil, fmt.Errorf("invalid weight in %q", line)
+		}
+
+		r := delayRange{weight: weight}
+
+		if r.min, err = time.ParseDuration(fields[1]); err != nil {
+			return nil, err
+		}
+
+		r.max = r.min
+
+		if len(fields) == 3 {
+			if r.max, err = time.ParseDuration(fields[2]); err != nil {
+				return nil, err
+			}
+		}
+
+		if r.min < 0 || r.max < r.min {
+			return nil, fmt.Errorf("invalid delay range in %q", line)
+		}
+
+		profile.ranges = append(profile.ranges, r)
+		profile.total += weight
+	}
+
+	if len(profile.ranges) == 0 {
+		return nil, fmt.Errorf("no delay range in %s", path)
+	}
+
+	return profile, nil
+}
+
+// sample draws a delay from the profile with rng
+func (p *TimingProfile) sample(rng *lockedRand) time.Duration {
+	pick := rng.Float64() * p.total
+
+	// the last range also catches rounding errors
+	r := p.ranges[len(p.ranges)-1]
+
+	for _, candidate := range p.ranges {
+		if pick < candidate.weight {
+			r = candidate
+			break
+		}
+
+		pick -= candidate.weight
+	}
+
+	return r.min + time.Duration(rng.Int63n(int64(r.max-r.min)+1))
+}
+
+// delayTransport waits for a delay drawn from the profile before every request.
+// It runs inside colly's parallelism slots, so requests of a thread are spread by the delays
+type delayTransport struct {
+	next    http.RoundTripper
+	profile *TimingProfile
+	rng     *lockedRand
+}
+
+func (t *delayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	timer := time.NewTimer(t.profile.sample(t.rng))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+
+	return t.next.RoundTrip(req)
+}
+
+// pauseTransport waits for -delay, plus up to -random-delay, after every request like colly's LimitRule does:
+// a request holds one of the slots until the delay after its body is closed is over.
+// Unlike colly's sleep, waiting for a slot ends as soon as the crawl is cancelled
+type pauseTransport struct {
+	next   http.RoundTripper
+	ctx    context.Context
+	delay  time.Duration
+	random time.Duration
+	rng    *lockedRand
+	slots  chan struct{}
+}
+
+func newPauseTransport(ctx context.Context, next http.RoundTripper, threads int, delay, random time.Duration, rng *lockedRand) *pauseTransport {
+	return &pauseTransport{next: next, ctx: ctx, delay: delay, random: random, rng: rng, slots: make(chan struct{}, max(threads, 1))}
+}
+
+func (t *pauseTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// colly's requests have no context, waiting ends with the crawl's
+	select {
+	case t.slots <- struct{}{}:
+	case <-t.ctx.Done():
+		return nil, t.ctx.Err()
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		t.release()
+		return nil, err
+	}
+
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: t.release}
+
+	return resp, nil
+}
+
+// release frees a slot once the delay is over
+func (t *pauseTransport) release() {
+	pause := t.delay
+	if t.random > 0 {
+		pause += time.Duration(t.rng.Int63n(int64(t.random)))
+	}
+
+	time.AfterFunc(pause, func() { <-t.slots })
+}
+
+// releaseBody calls release the first time it's closed
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+
+	return err
+}
@@ -0,0 +1,75 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// chainServer serves /0 linking to /1 and so on up to /last, and records when each page was requested
+func chainServer(last int) (*httptest.Server, func() []time.Time) {
+	var mu sync.Mutex
+	var times []time.Time
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+
+		w.Header().Set("Content-Type", "text/html")
+		if n < last {
+			fmt.Fprintf(w, `<html><body><a href="/%d">next</a></body></html>`, n+1)
+		}
+	}))
+
+	return server, func() []time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]time.Time(nil), times...)
+	}
+}
+
+func TestDelay(t *testing.T) {
+	server, times := chainServer(2)
+	defer server.Close()
+
+	cfg := &Config{Threads: 1, Depth: 3, SubsInScope: true, IgnoreRobots: true, Delay: time.Second, Timeout: 2 * time.Second}
+	StartCrawler(server.URL+"/0", cfg)
+
+	requested := times()
+	if len(requested) != 3 {
+		t.Fatalf("%d pages requested, want 3", len(requested))
+	}
+
+	for i := 1; i < len(requested); i++ {
+		if gap := requested[i].Sub(requested[i-1]); gap < 900*time.Millisecond || gap > 2*time.Second {
+			t.Errorf("page %d requested %v after the previous one, want about 1s", i, gap)
+		}
+	}
+}
+
+func TestDelayCancelled(t *testing.T) {
+	server, _ := chainServer(5)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	cfg := &Config{Threads: 1, Depth: 6, SubsInScope: true, IgnoreRobots: true, Delay: time.Minute, Timeout: 2 * time.Second}
+
+	start := time.Now()
+	StartCrawlerContext(ctx, server.URL+"/0", cfg)
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("the crawl returned %v after it was cancelled during a -delay of a minute", elapsed)
+	}
+}
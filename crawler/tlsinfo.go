@@ -0,0 +1,57 @@
+package crawler
+
+import (
+	"crypto/x509"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TLSInfo describes the certificate an HTTPS host presented
+type TLSInfo struct {
+	Host      string    `json:"host"`
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	SANs      []string  `json:"sans"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+}
+
+func newTLSInfo(host string, cert *x509.Certificate) TLSInfo {
+	sans := append([]string(nil), cert.DNSNames...)
+
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+
+	return TLSInfo{
+		Host:      host,
+		Subject:   cert.Subject.String(),
+		Issuer:    cert.Issuer.String(),
+		SANs:      sans,
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+	}
+}
+
+// tlsTransport reports the leaf certificate of every HTTPS host once.
+// colly's responses don't carry the TLS state, so it's picked up below colly
+type tlsTransport struct {
+	next   http.RoundTripper
+	report func(TLSInfo)
+	seen   sync.Map
+}
+
+func (t *tlsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+
+	if err == nil && resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		host := req.URL.Host
+
+		if _, reported := t.seen.LoadOrStore(host, true); !reported {
+			t.report(newTLSInfo(host, resp.TLS.PeerCertificates[0]))
+		}
+	}
+
+	return resp, err
+}
@@ -0,0 +1,223 @@
+package crawler
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// LoadCACert returns the system CAs plus the PEM certificates of path,
+// e.g. the CA of an interception proxy like Burp or ZAP
+func LoadCACert(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := x509.SystemCertPool()
+
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no PEM certificate found in " + path)
+	}
+
+	return pool, nil
+}
+
+// ParseProxy parses the -proxy URL, net/http speaks to http, https and socks5 proxies itself
+func ParseProxy(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, errors.New("unsupported proxy scheme in " + raw + ", use http, https or socks5")
+	}
+
+	if u.Host == "" {
+		return nil, errors.New("missing proxy host in " + raw)
+	}
+
+	return u, nil
+}
+
+// ParseProxies parses a comma-separated list of -proxy URLs
+func ParseProxies(list string) ([]*url.URL, error) {
+	proxies := make([]*url.URL, 0)
+
+	for _, raw := range strings.Split(list, ",") {
+		u, err := ParseProxy(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, err
+		}
+
+		proxies = append(proxies, u)
+	}
+
+	return proxies, nil
+}
+
+// roundRobinProxy sends each request through the next proxy of the list.
+// The transport keeps the connections of every proxy apart, they're reused per proxy
+func roundRobinProxy(proxies []*url.URL) func(*http.Request) (*url.URL, error) {
+	var next uint64
+
+	return func(req *http.Request) (*url.URL, error) {
+		n := atomic.AddUint64(&next, 1) - 1
+		return proxies[n%uint64(len(proxies))], nil
+	}
+}
+
+// hostTransport sends a Host header set with -h, net/http ignores a Host header and sends req.Host.
+// The other headers, hop-by-hop ones like Connection and Keep-Alive included, are sent as they are
+type hostTransport struct {
+	next http.RoundTripper
+}
+
+func (t *hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.Header.Get("Host")
+
+	if host == "" {
+		return t.next.RoundTrip(req)
+	}
+
+	out := req.Clone(req.Context())
+	out.Host = host
+	out.Header.Del("Host")
+
+	resp, err := t.next.RoundTrip(out)
+
+	// report the request as it was made by colly
+	if resp != nil {
+		resp.Request = req
+	}
+
+	return resp, err
+}
+
+// rawBodyTransport keeps compressed bodies as the server sent them, for -raw-body.
+// net/http doesn't decompress them with DisableCompression, but colly still gunzips
+// responses that aren't marked as already decompressed
+type rawBodyTransport struct {
+	next http.RoundTripper
+}
+
+func (t *rawBodyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+
+	if resp != nil {
+		resp.Uncompressed = true
+	}
+
+	return resp, err
+}
+
+// timeoutTransport bounds every single request, body included. The client's own timeout
+// would also count the waits between retries and the delays of -timing-profile
+type timeoutTransport struct {
+	next    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// the deadline keeps running until the body is closed
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	resp.Request = req
+
+	return resp, nil
+}
+
+// contextTransport runs the requests under the context of the crawl,
+// those in flight when it's done are cut short
+type contextTransport struct {
+	next http.RoundTripper
+	ctx  context.Context
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req.WithContext(t.ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Request = req
+
+	return resp, nil
+}
+
+// cancelBody releases the context of a request once its body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+
+	return err
+}
+
+// paramTransport adds query parameters to every outgoing request.
+// It works below colly, so the URLs colly resolves links against and records stay untouched
+type paramTransport struct {
+	next   http.RoundTripper
+	params url.Values
+}
+
+func (t *paramTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	query := req.URL.Query()
+	extra := url.Values{}
+
+	for key, values := range t.params {
+		// don't duplicate a parameter the URL already has
+		if _, ok := query[key]; !ok {
+			extra[key] = values
+		}
+	}
+
+	if len(extra) == 0 {
+		return t.next.RoundTrip(req)
+	}
+
+	u := *req.URL
+	if u.RawQuery != "" {
+		u.RawQuery += "&"
+	}
+	u.RawQuery += extra.Encode()
+
+	out := req.Clone(req.Context())
+	out.URL = &u
+
+	resp, err := t.next.RoundTrip(out)
+
+	// report the request as it was made by colly
+	if resp != nil {
+		resp.Request = req
+	}
+
+	return resp, err
+}
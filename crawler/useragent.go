@@ -0,0 +1,46 @@
+package crawler
+
+import "strings"
+
+// userAgentPresets are the user agents -ua takes by name
+var userAgentPresets = map[string]string{
+	"desktop":   defaultUserAgent,
+	"mobile":    "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36",
+	"googlebot": "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+}
+
+// rotationUserAgents are the current browsers -ua-rand picks from for every request
+var rotationUserAgents = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:125.0) Gecko/20100101 Firefox/125.0",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.0.0",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0",
+	"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+	"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36",
+}
+
+// resolveUserAgent returns the user agent of a preset name (desktop, mobile, googlebot),
+// anything else is the header itself. Empty is the default user agent
+func resolveUserAgent(ua string) string {
+	if ua == "" {
+		return defaultUserAgent
+	}
+
+	if preset, ok := userAgentPresets[strings.ToLower(strings.TrimSpace(ua))]; ok {
+		return preset
+	}
+
+	return ua
+}
+
+// userAgent returns the user agent of the next request of the crawl, a new pick each time with -ua-rand
+func (cr *crawl) userAgent() string {
+	if cr.cfg.RandomAgent {
+		return rotationUserAgents[cr.rng.Int63n(int64(len(rotationUserAgents)))]
+	}
+
+	return resolveUserAgent(cr.cfg.UserAgent)
+}
@@ -0,0 +1,127 @@
+package crawler
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+)
+
+// ValidatorStore keeps the ETag and Last-Modified of crawled pages between runs,
+// re-crawls send them back so unchanged pages answer 304 Not Modified
+type ValidatorStore struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]validator
+}
+
+// validator is a line of the -validators file
+type validator struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// LoadValidatorStore reads the validators saved by a previous run, a missing file is an empty store
+func LoadValidatorStore(path string) (*ValidatorStore, error) {
+	store := &ValidatorStore{path: path, entries: make(map[string]validator)}
+
+	f, err := os.Open(path)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return store, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for s.Scan() {
+		var v validator
+
+		if err := json.Unmarshal(s.Bytes(), &v); err != nil {
+			return nil, err
+		}
+
+		store.entries[v.URL] = v
+	}
+
+	return store, s.Err()
+}
+
+// condition sets the conditional headers of a request to a page seen by a previous run
+func (vs *ValidatorStore) condition(link string, headers *http.Header) {
+	vs.mu.Lock()
+	v, ok := vs.entries[link]
+	vs.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	if v.ETag != "" {
+		headers.Set("If-None-Match", v.ETag)
+	}
+
+	if v.LastModified != "" {
+		headers.Set("If-Modified-Since", v.LastModified)
+	}
+}
+
+// update remembers the validators of a fetched page, pages without any are forgotten
+func (vs *ValidatorStore) update(link string, headers *http.Header) {
+	v := validator{URL: link, ETag: headers.Get("ETag"), LastModified: headers.Get("Last-Modified")}
+
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	if v.ETag == "" && v.LastModified == "" {
+		delete(vs.entries, link)
+	} else {
+		vs.entries[link] = v
+	}
+}
+
+// Save writes the store back to its file, one JSON object per page
+func (vs *ValidatorStore) Save() error {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	links := make([]string, 0, len(vs.entries))
+	for link := range vs.entries {
+		links = append(links, link)
+	}
+	sort.Strings(links)
+
+	f, err := os.Create(vs.path)
+
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	for _, link := range links {
+		if err := enc.Encode(vs.entries[link]); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,91 @@
+package crawler
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// verify requests the recorded http(s) URLs with the crawl's threads and records their status.
+// With -live-only, only the URLs answering with a status below 400 are kept
+func (cr *crawl) verify(results []Result) []Result {
+	var wg sync.WaitGroup
+
+	jobs := make(chan int)
+
+	for i := 0; i < cr.workers(); i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for idx := range jobs {
+				// once the crawl is cancelled the rest stays unverified
+				if !cr.cancelled() {
+					cr.limited(func() {
+						results[idx].Status = cr.status(results[idx].URL)
+					})
+				}
+			}
+		}()
+	}
+
+	for idx, result := range results {
+		if IsWebURL(result.URL) {
+			jobs <- idx
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	if !cr.cfg.LiveOnly {
+		return results
+	}
+
+	live := make([]Result, 0, len(results))
+
+	for _, result := range results {
+		if result.Status > 0 && result.Status < 400 {
+			live = append(live, result)
+		}
+	}
+
+	return live
+}
+
+// status returns the status of link after redirects, or 0 if it couldn't be requested.
+// HEAD is tried first, some servers don't implement it though
+func (cr *crawl) status(link string) int {
+	res, err := cr.do("HEAD", link)
+
+	if err == nil && res.StatusCode != http.StatusMethodNotAllowed && res.StatusCode != http.StatusNotImplemented {
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	if err == nil {
+		res.Body.Close()
+	}
+
+	res, err = cr.get(link)
+
+	if err != nil {
+		return 0
+	}
+
+	defer res.Body.Close()
+
+	// drain a little of the body so the connection can be reused
+	io.CopyN(io.Discard, res.Body, 4096)
+
+	return res.StatusCode
+}
+
+// IsWebURL reports whether link is an absolute http(s) URL
+func IsWebURL(link string) bool {
+	u, err := url.Parse(link)
+
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}